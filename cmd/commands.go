@@ -0,0 +1,105 @@
+package main
+
+import (
+	"effective_mobile_testing/internal/auth"
+	"effective_mobile_testing/internal/backup"
+	"effective_mobile_testing/internal/model"
+
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const usage = `usage:
+  service                         run HTTP server
+  service backup  [-file path]    dump persons, tasks and history as JSON lines (stdout by default),
+                                  without teams, memberships, API keys and webhooks
+  service restore [-file path]    restore a dump into an empty database (stdin by default),
+                                  then create an admin key with apikey and recreate the teams
+  service apikey  -name n [-roles admin,personal_data] [-person id]
+                                  create an API key and print it, e.g. the first admin key`
+
+// runCommand handles CLI subcommands. It returns false when no subcommand was given and the server should start.
+func runCommand(db *sqlx.DB, keys *auth.APIKeys, args []string) (bool, error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+
+	switch args[0] {
+	case "backup":
+		fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+		file := fs.String("file", "", "output file, stdout if empty")
+		if err := fs.Parse(args[1:]); err != nil {
+			return true, err
+		}
+
+		var w io.Writer = os.Stdout
+		if *file != "" {
+			f, err := os.Create(*file)
+			if err != nil {
+				return true, err
+			}
+			defer f.Close()
+			w = f
+		}
+
+		if err := backup.Dump(db, w); err != nil {
+			return true, err
+		}
+		slog.Info("backup finished")
+
+		return true, nil
+	case "restore":
+		fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+		file := fs.String("file", "", "input file, stdin if empty")
+		if err := fs.Parse(args[1:]); err != nil {
+			return true, err
+		}
+
+		var r io.Reader = os.Stdin
+		if *file != "" {
+			f, err := os.Open(*file)
+			if err != nil {
+				return true, err
+			}
+			defer f.Close()
+			r = f
+		}
+
+		if err := backup.Restore(db, r); err != nil {
+			return true, err
+		}
+		slog.Info("restore finished")
+
+		return true, nil
+	case "apikey":
+		fs := flag.NewFlagSet("apikey", flag.ContinueOnError)
+		name := fs.String("name", "", "key name")
+		roles := fs.String("roles", "", "comma separated roles")
+		person := fs.Int64("person", 0, "person id, required for manager and employee keys")
+		if err := fs.Parse(args[1:]); err != nil {
+			return true, err
+		}
+
+		req := model.CreateAPIKeyRequest{Name: *name, PersonID: *person}
+		if *roles != "" {
+			req.Roles = strings.Split(*roles, ",")
+		}
+
+		key, err := keys.Bootstrap(req)
+		if err != nil {
+			return true, err
+		}
+		fmt.Println(key.Key)
+		slog.Info("api key created", slog.Int64("id", key.ID), slog.String("prefix", key.Prefix))
+
+		return true, nil
+	default:
+		return true, fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+}
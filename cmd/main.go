@@ -1,12 +1,21 @@
 package main
 
 import (
+	"context"
 	_ "effective_mobile_testing/docs"
+	"effective_mobile_testing/internal/auth"
 	"effective_mobile_testing/internal/config"
 	"effective_mobile_testing/internal/connection"
 	"effective_mobile_testing/internal/handlers"
+	"effective_mobile_testing/internal/mask"
+	"effective_mobile_testing/internal/outbox"
+	"effective_mobile_testing/internal/ratelimit"
+	"effective_mobile_testing/internal/registry"
+	"effective_mobile_testing/internal/scheduler"
+	"effective_mobile_testing/internal/secure"
 	"effective_mobile_testing/internal/service"
 	"effective_mobile_testing/internal/service/repository"
+	"effective_mobile_testing/internal/webhook"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -24,7 +33,14 @@ import (
 // @BasePath		/
 
 func main() {
-	loggerSlog := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	// subcommands may write their output to stdout, so keep logs out of it
+	logOut := os.Stdout
+	if len(os.Args) > 1 {
+		logOut = os.Stderr
+	}
+
+	// personal data and secrets are masked in every log line, see internal/mask
+	loggerSlog := slog.New(mask.NewHandler(slog.NewTextHandler(logOut, &slog.HandlerOptions{Level: slog.LevelDebug})))
 	slog.SetDefault(loggerSlog)
 
 	if err := godotenv.Load("./.env"); err != nil {
@@ -32,8 +48,16 @@ func main() {
 		return
 	}
 
+	// passports are never stored in plain text, so there is nothing to run without the keys
+	keys, activeKey := config.GetPassportKeys()
+	passports, err := secure.NewCipher(keys, activeKey, config.GetPassportIndexKey())
+	if err != nil {
+		slog.Error("invalid passport encryption config, set PASSPORT_KEYS and PASSPORT_INDEX_KEY (see .env.example)", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+
 	dbConfig := config.GetDBConfig()
-	slog.Debug(fmt.Sprintf("dbConfig: %v", dbConfig))
+	slog.Debug(fmt.Sprintf("dbConfig: %v", mask.DSN(dbConfig)))
 
 	db, err := connection.NewPostgresDB(dbConfig)
 	if err != nil {
@@ -41,26 +65,122 @@ func main() {
 	}
 	slog.Debug("postgres connection created")
 
-	repo := repository.NewUserTaskRepo(db)
-	userTaskService := service.NewUserTaskService(repo)
-	handler := handlers.NewHandlers(userTaskService)
+	repo := repository.NewUserTaskRepo(db, passports)
+	dispatcher := webhook.NewDispatcher(repo)
+	registryCache := registry.NewCache(registry.NewClient(), repo)
+	userTaskService := service.NewUserTaskService(repo, registryCache)
+	apiKeys := auth.NewAPIKeys(repo)
+	handler := handlers.NewHandlers(userTaskService, dispatcher, apiKeys, userTaskService)
+
+	authenticators := auth.Chain{apiKeys}
+	if secret, jwksFile := config.GetJWTSecret(), config.GetJWKSFile(); secret != "" || jwksFile != "" {
+		tokens, err := auth.NewJWT(secret, jwksFile, config.GetJWTIssuer(), config.GetJWTAudience())
+		if err != nil {
+			slog.Error("invalid jwt config:", slog.String("err", err.Error()))
+			return
+		}
+		authenticators = append(authenticators, tokens)
+	}
 
 	if err := connection.InitSchema(db); err != nil {
 		return
 	}
 	slog.Debug("schema initialized")
 
-	r := gin.Default()
+	// encrypts passports stored before encryption and moves old ones to the active key
+	sealed, err := repo.SealPassports()
+	if err != nil {
+		slog.Error("can't encrypt passports:", slog.String("err", err.Error()))
+		return
+	}
+	if sealed > 0 {
+		slog.Info("passports encrypted with the active key", slog.Int64("count", sealed), slog.String("key", activeKey))
+	}
+
+	if ok, err := runCommand(db, apiKeys, os.Args[1:]); ok {
+		if err != nil {
+			slog.Error("command failed", slog.String("err", err.Error()))
+			os.Exit(1)
+		}
+		return
+	}
+
+	sinks := []outbox.Sink{dispatcher}
+	if natsURL := config.GetNATSURL(); natsURL != "" {
+		natsSink, err := outbox.NewNATSSink(natsURL, config.GetNATSSubjectPrefix())
+		if err != nil {
+			slog.Error("can't connect to NATS:", slog.String("err", err.Error()))
+			return
+		}
+		defer natsSink.Close()
+
+		sinks = append(sinks, natsSink)
+		slog.Debug("nats connection created")
+	}
+
+	relay := outbox.NewRelay(repo, sinks...)
+	go relay.Run(context.Background())
+	go dispatcher.Run(context.Background())
+	go scheduler.Every(context.Background(), config.GetPurgeInterval(), "purge deleted users", userTaskService.PurgeDeletedUsers)
+	go scheduler.Every(context.Background(), config.GetRegistrySyncInterval(), "registry sync", userTaskService.SyncAllUsers)
+	go scheduler.Every(context.Background(), config.GetPurgeInterval(), "purge registry cache", registryCache.Purge)
+	go scheduler.Every(context.Background(), config.GetPurgeInterval(), "purge outbox", relay.Purge)
+
+	r := gin.New()
+	r.Use(handlers.RequestLogger(), handlers.Recovery())
 
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	r.POST("/user/create/", handler.CreateUser())
-	r.PATCH("/user/start-tracking/", handler.StartTracking())
-	r.PATCH("/user/stop-tracking/", handler.StopTracking())
-	r.GET("/user/get-costs/", handler.GetLaborCosts())
-	r.GET("/users/", handler.GetUserByFilters())
-	r.DELETE("/user/", handler.DeleteUser())
-	r.PATCH("/user/", handler.UpdateUser())
+	// everything except the docs needs an API key or a bearer token. The IP limit runs first,
+	// so failed credentials are limited before they cost a key lookup
+	api := r.Group("/",
+		handlers.RateLimit(ratelimit.New(config.GetIPRateLimit())),
+		handlers.Authenticate(authenticators),
+		handlers.RateLimit(ratelimit.New(config.GetRateLimit())),
+	)
+	// routes calling the passport registry or building reports have a lower limit on top
+	expensive := handlers.RateLimit(ratelimit.New(config.GetExpensiveRateLimit()))
+
+	api.POST("/user/create/", expensive, handler.CreateUser())
+	api.PATCH("/user/start-tracking/", handler.StartTracking())
+	api.PATCH("/user/stop-tracking/", handler.StopTracking())
+	api.GET("/user/get-costs/", expensive, handler.GetLaborCosts())
+	api.GET("/users/", handler.GetUserByFilters())
+	api.DELETE("/user/", handler.DeleteUser())
+	api.PATCH("/user/", handler.UpdateUser())
+	api.POST("/user/restore/", handler.RestoreUser())
+	api.GET("/user/:id", handler.GetUserDetail())
+	api.GET("/user/:id/history", handler.GetUserHistory())
+	api.POST("/user/:id/sync", expensive, handler.SyncUser())
+	api.GET("/user/:id/duplicates", handler.GetDuplicates())
+	api.POST("/users/merge/", handler.MergeUsers())
+	api.GET("/user/:id/export", expensive, handler.ExportUser())
+	api.POST("/user/:id/erase", handler.EraseUser())
+	api.GET("/users/sync/", handler.GetSyncReport())
+	api.POST("/users/bulk/", expensive, handler.BulkCreateUsers())
+	api.GET("/users/bulk/", handler.GetBulkJob())
+
+	api.POST("/teams/", handler.CreateTeam())
+	api.GET("/teams/", handler.GetTeams())
+	api.GET("/team/:id", handler.GetTeam())
+	api.DELETE("/team/:id", handler.DeleteTeam())
+	api.POST("/team/:id/members", handler.AddTeamMember())
+	api.DELETE("/team/:id/members", handler.EndTeamMember())
+	api.GET("/team/:id/report", expensive, handler.GetTeamReport())
+
+	api.GET("/audit/access/", handler.GetAccessAudit())
+
+	api.GET("/registry/cache/", handler.GetRegistryCacheStats())
+
+	api.POST("/apikeys/", handler.CreateAPIKey())
+	api.GET("/apikeys/", handler.GetAPIKeys())
+	api.DELETE("/apikeys/", handler.RevokeAPIKey())
+
+	api.POST("/webhooks/", handler.CreateWebhook())
+	api.GET("/webhooks/", handler.GetWebhooks())
+	api.DELETE("/webhooks/", handler.DeleteWebhook())
+	api.GET("/webhooks/deliveries/", handler.GetWebhookDeliveries())
+	api.POST("/webhooks/deliveries/replay/", handler.ReplayWebhookDelivery())
 
 	if err := r.Run(":8080"); err != nil {
 		log.Fatal(err)
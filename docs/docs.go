@@ -15,9 +15,20 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/user/": {
-            "delete": {
-                "description": "delete user data",
+        "/apikeys/": {
+            "get": {
+                "description": "list API keys with their prefixes, admin only",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "List API keys",
+                "responses": {}
+            },
+            "post": {
+                "description": "create an API key, admin only. The key is returned only here",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,22 +36,164 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "auth"
                 ],
-                "summary": "Delete User",
+                "summary": "Create API key",
+                "parameters": [
+                    {
+                        "description": "api key",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.CreateAPIKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {}
+            },
+            "delete": {
+                "description": "revoke an API key, admin only",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Revoke API key",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID",
+                        "description": "API key ID",
                         "name": "id",
                         "in": "query",
                         "required": true
                     }
                 ],
                 "responses": {}
+            }
+        },
+        "/audit/access/": {
+            "get": {
+                "description": "who read personal data through /users/, /user/{id} and exports, newest first. Admin only",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "audit"
+                ],
+                "summary": "Access audit",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Caller name, e.g. apikey:reports",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "users.list, user.detail or user.export",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Records that returned this user",
+                        "name": "person_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Since, YYYY-MM-DD or RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Before, YYYY-MM-DD or RFC 3339",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, capped by PAGE_MAX_SIZE",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor from the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Count all matching records",
+                        "name": "total",
+                        "in": "query"
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/registry/cache/": {
+            "get": {
+                "description": "passport registry cache hits and misses counted by this instance since start. Admin only",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "registry"
+                ],
+                "summary": "Registry cache stats",
+                "responses": {}
+            }
+        },
+        "/team/{id}": {
+            "get": {
+                "description": "team with all membership periods, for its managers and admins",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "teams"
+                ],
+                "summary": "Get team",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Team ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
             },
-            "patch": {
-                "description": "update user data",
+            "delete": {
+                "description": "delete the team with its memberships, admin only. Teams of a deleted department move to the top level",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "teams"
+                ],
+                "summary": "Delete team",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Team ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/team/{id}/members": {
+            "post": {
+                "description": "add a member or a manager for a period, admin only. Periods of one person in a team can't overlap",
                 "consumes": [
                     "application/json"
                 ],
@@ -48,31 +201,111 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "teams"
                 ],
-                "summary": "Update User",
+                "summary": "Add team member",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "User ID",
+                        "type": "integer",
+                        "description": "Team ID",
                         "name": "id",
-                        "in": "query"
+                        "in": "path",
+                        "required": true
                     },
                     {
-                        "description": "user udpate data",
+                        "description": "membership",
                         "name": "input",
                         "in": "body",
+                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.UserUpdateRequest"
+                            "$ref": "#/definitions/model.AddTeamMemberRequest"
                         }
                     }
                 ],
                 "responses": {}
+            },
+            "delete": {
+                "description": "end the membership of the person at the date (today by default), admin only. Earlier time stays in reports",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "teams"
+                ],
+                "summary": "Remove team member",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Team ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "person_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date, YYYY-MM-DD, exclusive",
+                        "name": "date",
+                        "in": "query"
+                    }
+                ],
+                "responses": {}
             }
         },
-        "/user/create/": {
+        "/team/{id}/report": {
+            "get": {
+                "description": "time tracked by the members while they were in the team (with the teams of a department), for its managers and admins",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "teams"
+                ],
+                "summary": "Team labor report",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Team ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Period start, YYYY-MM-DD or RFC 3339, start of the month by default",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Period end, exclusive, YYYY-MM-DD or RFC 3339, now by default",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/teams/": {
+            "get": {
+                "description": "teams and departments, for managers and admins",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "teams"
+                ],
+                "summary": "List teams",
+                "responses": {}
+            },
             "post": {
-                "description": "create user by passportNumber",
+                "description": "create a team or a department, admin only. A team may belong to a department via parent_id",
                 "consumes": [
                     "application/json"
                 ],
@@ -80,26 +313,26 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "teams"
                 ],
-                "summary": "Create User",
+                "summary": "Create team",
                 "parameters": [
                     {
-                        "description": "passport number",
+                        "description": "team",
                         "name": "input",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.CreateUserRequest"
+                            "$ref": "#/definitions/model.CreateTeamRequest"
                         }
                     }
                 ],
                 "responses": {}
             }
         },
-        "/user/get-costs/": {
-            "get": {
-                "description": "get info about working",
+        "/user/": {
+            "delete": {
+                "description": "soft delete user, tasks are kept. The user can be restored until the retention purge",
                 "consumes": [
                     "application/json"
                 ],
@@ -109,24 +342,30 @@ const docTemplate = `{
                 "tags": [
                     "users"
                 ],
-                "summary": "Get labor cost",
+                "summary": "Delete User",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "User ID",
+                        "description": "ID",
                         "name": "id",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the user or *",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
                     }
                 ],
                 "responses": {}
-            }
-        },
-        "/user/start-tracking/": {
+            },
             "patch": {
-                "description": "start time for task",
+                "description": "update user data with JSON Merge Patch: omitted fields are kept, null clears patronymic",
                 "consumes": [
-                    "application/json"
+                    "application/json",
+                    "application/merge-patch+json"
                 ],
                 "produces": [
                     "application/json"
@@ -134,24 +373,36 @@ const docTemplate = `{
                 "tags": [
                     "users"
                 ],
-                "summary": "Start tracking",
+                "summary": "Update User",
                 "parameters": [
                     {
-                        "description": "choose task and user",
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the user or *",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "user update data",
                         "name": "input",
                         "in": "body",
-                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.RequestStartTracking"
+                            "$ref": "#/definitions/model.UserPatch"
                         }
                     }
                 ],
                 "responses": {}
             }
         },
-        "/user/stop-tracking/": {
-            "patch": {
-                "description": "stop time for task",
+        "/user/create/": {
+            "post": {
+                "description": "create user by passportNumber",
                 "consumes": [
                     "application/json"
                 ],
@@ -161,24 +412,24 @@ const docTemplate = `{
                 "tags": [
                     "users"
                 ],
-                "summary": "Stop tracking",
+                "summary": "Create User",
                 "parameters": [
                     {
-                        "description": "choose task and user",
+                        "description": "passport number",
                         "name": "input",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.RequestStopTracking"
+                            "$ref": "#/definitions/model.CreateUserRequest"
                         }
                     }
                 ],
                 "responses": {}
             }
         },
-        "/users/": {
+        "/user/get-costs/": {
             "get": {
-                "description": "Get info by any filters",
+                "description": "get info about working",
                 "consumes": [
                     "application/json"
                 ],
@@ -188,34 +439,316 @@ const docTemplate = `{
                 "tags": [
                     "users"
                 ],
-                "summary": "Get Users",
+                "summary": "Get labor cost",
                 "parameters": [
                     {
                         "type": "string",
                         "description": "User ID",
                         "name": "id",
-                        "in": "query"
+                        "in": "query",
+                        "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Surname",
-                        "name": "surname",
+                        "type": "integer",
+                        "description": "Page size, capped by PAGE_MAX_SIZE",
+                        "name": "limit",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "Name",
-                        "name": "name",
+                        "description": "next_cursor from the previous page",
+                        "name": "cursor",
                         "in": "query"
                     },
                     {
-                        "type": "string",
-                        "description": "Patronymic",
-                        "name": "patronymic",
+                        "type": "boolean",
+                        "description": "Count all tasks",
+                        "name": "total",
                         "in": "query"
-                    },
-                    {
-                        "type": "string",
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/user/restore/": {
+            "post": {
+                "description": "restore soft deleted user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Restore User",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/user/start-tracking/": {
+            "patch": {
+                "description": "start time for task",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Start tracking",
+                "parameters": [
+                    {
+                        "description": "choose task and user",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.RequestStartTracking"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/user/stop-tracking/": {
+            "patch": {
+                "description": "stop time for task",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Stop tracking",
+                "parameters": [
+                    {
+                        "description": "choose task and user",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.RequestStopTracking"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/user/{id}": {
+            "get": {
+                "description": "user with the running task, minutes tracked today, this week and this month and the recent tasks. Ids of merged users return the survivor",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "User detail",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/user/{id}/duplicates": {
+            "get": {
+                "description": "persons that look like the same human: similar full name, similar address, same passport number",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Duplicate candidates",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/user/{id}/erase": {
+            "post": {
+                "description": "anonymize the person and persons merged into it, remove personal fields from history and stored events. Tracked time is kept",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "privacy"
+                ],
+                "summary": "Erase personal data",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/user/{id}/export": {
+            "get": {
+                "description": "person record, all tracked tasks and the change history as JSON or a ZIP with one file per part. The export is recorded in the history",
+                "produces": [
+                    "application/json",
+                    "application/zip"
+                ],
+                "tags": [
+                    "privacy"
+                ],
+                "summary": "Export personal data",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "json (default) or zip",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/user/{id}/history": {
+            "get": {
+                "description": "changes of user data with actor and old/new values, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "User history",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, capped by PAGE_MAX_SIZE",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor from the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Count all changes",
+                        "name": "total",
+                        "in": "query"
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/user/{id}/sync": {
+            "post": {
+                "description": "re-fetch person data from the passport registry and return the field diff. With apply=true the diff is saved",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Sync user with registry",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Save the registry data",
+                        "name": "apply",
+                        "in": "query"
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/users/": {
+            "get": {
+                "description": "Get info by any filters",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get Users",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Surname",
+                        "name": "surname",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Patronymic",
+                        "name": "patronymic",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
                         "description": "Address",
                         "name": "address",
                         "in": "query"
@@ -226,17 +759,320 @@ const docTemplate = `{
                         "name": "passport_number",
                         "in": "query"
                     },
+                    {
+                        "enum": [
+                            "exact",
+                            "prefix",
+                            "contains",
+                            "fuzzy"
+                        ],
+                        "type": "string",
+                        "description": "Default match mode for text fields",
+                        "name": "match",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "exact",
+                            "prefix",
+                            "contains",
+                            "fuzzy"
+                        ],
+                        "type": "string",
+                        "description": "Surname match mode",
+                        "name": "surname_match",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "exact",
+                            "prefix",
+                            "contains",
+                            "fuzzy"
+                        ],
+                        "type": "string",
+                        "description": "Name match mode",
+                        "name": "name_match",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "exact",
+                            "prefix",
+                            "contains",
+                            "fuzzy"
+                        ],
+                        "type": "string",
+                        "description": "Patronymic match mode",
+                        "name": "patronymic_match",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "exact",
+                            "prefix",
+                            "contains",
+                            "fuzzy"
+                        ],
+                        "type": "string",
+                        "description": "Address match mode",
+                        "name": "address_match",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Search in surname, name, patronymic and address",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated columns, - for descending, e.g. surname,-id",
+                        "name": "sort",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "Limit",
+                        "description": "Page size, capped by PAGE_MAX_SIZE",
                         "name": "limit",
                         "in": "query"
                     },
+                    {
+                        "type": "string",
+                        "description": "next_cursor from the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Count all matching users",
+                        "name": "total",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "Offset",
+                        "description": "Rows to skip, can't be combined with cursor",
                         "name": "offset",
                         "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include soft deleted users",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/users/bulk/": {
+            "get": {
+                "description": "get per-row results of a bulk create job",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Bulk job status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            },
+            "post": {
+                "description": "create users from a list of passport numbers as an async job. Accepts JSON, text/csv body or multipart \"file\" with one passport per row",
+                "consumes": [
+                    "application/json",
+                    "text/csv",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Bulk create users",
+                "parameters": [
+                    {
+                        "description": "passport numbers",
+                        "name": "input",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/model.BulkCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/users/merge/": {
+            "post": {
+                "description": "move tasks of the duplicate to the survivor and delete the duplicate. The duplicate id keeps resolving to the survivor",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Merge users",
+                "parameters": [
+                    {
+                        "description": "survivor and duplicate ids",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.MergeUsersRequest"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/users/sync/": {
+            "get": {
+                "description": "persons whose registry data changed in the last scheduled re-sync",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Registry sync report",
+                "responses": {}
+            }
+        },
+        "/webhooks/": {
+            "get": {
+                "description": "list webhook subscriptions",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List webhooks",
+                "responses": {}
+            },
+            "post": {
+                "description": "subscribe url to events. Secret is generated when empty and returned only here",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Create webhook",
+                "parameters": [
+                    {
+                        "description": "webhook",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.CreateWebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {}
+            },
+            "delete": {
+                "description": "delete webhook subscription with its delivery log",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Delete webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/webhooks/deliveries/": {
+            "get": {
+                "description": "delivery log, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Webhook deliveries",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "webhook_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "pending, delivered or failed",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, capped by PAGE_MAX_SIZE",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor from the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Count all matching deliveries",
+                        "name": "total",
+                        "in": "query"
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/webhooks/deliveries/replay/": {
+            "post": {
+                "description": "send a stored delivery again as a new delivery",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Replay delivery",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Delivery ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
                     }
                 ],
                 "responses": {}
@@ -244,6 +1080,65 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "model.AddTeamMemberRequest": {
+            "type": "object",
+            "properties": {
+                "person_id": {
+                    "type": "integer"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "valid_from": {
+                    "type": "string"
+                },
+                "valid_to": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.BulkCreateRequest": {
+            "type": "object",
+            "properties": {
+                "passport_numbers": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "model.CreateAPIKeyRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "person_id": {
+                    "type": "integer"
+                },
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "model.CreateTeamRequest": {
+            "type": "object",
+            "properties": {
+                "kind": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "parent_id": {
+                    "type": "integer"
+                }
+            }
+        },
         "model.CreateUserRequest": {
             "type": "object",
             "properties": {
@@ -252,6 +1147,34 @@ const docTemplate = `{
                 }
             }
         },
+        "model.CreateWebhookRequest": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.MergeUsersRequest": {
+            "type": "object",
+            "properties": {
+                "duplicate_id": {
+                    "type": "integer"
+                },
+                "survivor_id": {
+                    "type": "integer"
+                }
+            }
+        },
         "model.RequestStartTracking": {
             "type": "object",
             "properties": {
@@ -274,7 +1197,7 @@ const docTemplate = `{
                 }
             }
         },
-        "model.UserUpdateRequest": {
+        "model.UserPatch": {
             "type": "object",
             "properties": {
                 "address": {
@@ -303,7 +1226,7 @@ var SwaggerInfo = &swag.Spec{
 	Host:             "localhost:8080",
 	BasePath:         "/",
 	Schemes:          []string{},
-	Title:            "Time-tracker",
+	Title:            "Time-tracker API",
 	Description:      "Effective mobile testing",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
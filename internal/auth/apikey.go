@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"effective_mobile_testing/internal/model"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+const (
+	apiKeyHeader = "X-API-Key"
+	apiKeyScheme = "ApiKey "
+	apiKeyPrefix = "tt_"
+)
+
+var (
+	ErrKeyNotFound = errors.New("api key not found")
+	ErrInvalidKey  = errors.New("api key needs a name and known roles")
+)
+
+type KeyRepository interface {
+	CreateAPIKey(name, prefix, hash string, roles []string, personID int64) (*model.APIKey, error)
+	ListAPIKeys() ([]model.APIKey, error)
+	RevokeAPIKey(id int64) error
+	// GetAPIKeyByHash returns active keys only and marks the key as used.
+	GetAPIKeyByHash(hash string) (*model.APIKey, error)
+}
+
+// APIKeys authenticates requests by static API keys and manages them. Keys are random,
+// so a plain SHA-256 is enough to store them.
+type APIKeys struct {
+	repo KeyRepository
+}
+
+func NewAPIKeys(repo KeyRepository) *APIKeys {
+	return &APIKeys{repo: repo}
+}
+
+// Authenticate reads the key from X-API-Key or "Authorization: ApiKey <key>".
+func (k *APIKeys) Authenticate(r *http.Request) (Principal, error) {
+	key := r.Header.Get(apiKeyHeader)
+	if h := r.Header.Get("Authorization"); key == "" && strings.HasPrefix(h, apiKeyScheme) {
+		key = strings.TrimPrefix(h, apiKeyScheme)
+	}
+	if key == "" {
+		return Principal{}, ErrNoCredentials
+	}
+
+	apiKey, err := k.repo.GetAPIKeyByHash(HashKey(key))
+	if err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return Principal{}, ErrInvalidCredentials
+		}
+		return Principal{}, err
+	}
+
+	p := Principal{Name: "apikey:" + apiKey.Name, Roles: apiKey.Roles}
+	if apiKey.PersonID != nil {
+		p.PersonID = *apiKey.PersonID
+	}
+
+	return p, nil
+}
+
+// Create makes a new key. The key is returned only here, the database keeps its hash.
+func (k *APIKeys) Create(ctx context.Context, req model.CreateAPIKeyRequest) (*model.CreatedAPIKey, error) {
+	if !HasRole(ctx, RoleAdmin) {
+		return nil, ErrForbidden
+	}
+
+	return k.create(req)
+}
+
+// Bootstrap creates a key without checking the caller, for the CLI.
+func (k *APIKeys) Bootstrap(req model.CreateAPIKeyRequest) (*model.CreatedAPIKey, error) {
+	return k.create(req)
+}
+
+func (k *APIKeys) List(ctx context.Context) ([]model.APIKey, error) {
+	if !HasRole(ctx, RoleAdmin) {
+		return nil, ErrForbidden
+	}
+
+	return k.repo.ListAPIKeys()
+}
+
+func (k *APIKeys) Revoke(ctx context.Context, id int64) error {
+	if !HasRole(ctx, RoleAdmin) {
+		return ErrForbidden
+	}
+
+	if err := k.repo.RevokeAPIKey(id); err != nil {
+		return err
+	}
+
+	slog.Info("api key revoked", slog.Int64("id", id), slog.String("actor", Actor(ctx)))
+
+	return nil
+}
+
+func (k *APIKeys) create(req model.CreateAPIKeyRequest) (*model.CreatedAPIKey, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, ErrInvalidKey
+	}
+	for _, r := range req.Roles {
+		if !IsRole(r) {
+			return nil, fmt.Errorf("%w: unknown role %q", ErrInvalidKey, r)
+		}
+		if (r == RoleManager || r == RoleEmployee) && req.PersonID == 0 {
+			return nil, fmt.Errorf("%w: %s key needs person_id", ErrInvalidKey, r)
+		}
+	}
+	roles := req.Roles
+	if roles == nil {
+		roles = []string{}
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	apiKey, err := k.repo.CreateAPIKey(req.Name, key[:len(apiKeyPrefix)+6], HashKey(key), roles, req.PersonID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.CreatedAPIKey{APIKey: *apiKey, Key: key}, nil
+}
+
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+)
+
+var (
+	// ErrNoCredentials means the request carries no credentials this authenticator understands.
+	ErrNoCredentials = errors.New("no credentials")
+	// ErrInvalidCredentials means credentials were given but are wrong, expired or revoked.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrForbidden means the caller is known but lacks the role for the action.
+	ErrForbidden = errors.New("forbidden")
+)
+
+// Authenticator resolves the caller of an HTTP request.
+type Authenticator interface {
+	Authenticate(r *http.Request) (Principal, error)
+}
+
+// Chain tries authenticators in order and returns the first principal found.
+// ErrNoCredentials is returned only when none of them recognized the request.
+type Chain []Authenticator
+
+func (c Chain) Authenticate(r *http.Request) (Principal, error) {
+	for _, a := range c {
+		p, err := a.Authenticate(r)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+
+		return p, err
+	}
+
+	return Principal{}, ErrNoCredentials
+}
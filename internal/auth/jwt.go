@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const bearerScheme = "Bearer "
+
+var ErrNoJWTKeys = errors.New("jwt needs a shared secret or a JWKS file")
+
+// JWT authenticates bearer tokens signed with a shared secret (HS256) or with a key
+// from a JWKS file (RS256/ES256, selected by kid). The subject becomes the principal
+// name, the "roles" claim its roles and the "person_id" claim its person.
+type JWT struct {
+	secret []byte
+	keys   map[string]any
+	parser *jwt.Parser
+}
+
+type jwtClaims struct {
+	jwt.RegisteredClaims
+	Roles    []string `json:"roles"`
+	PersonID int64    `json:"person_id"`
+}
+
+// NewJWT takes the shared secret and the JWKS file path, either may be empty but not both.
+// Issuer and audience are checked when set.
+func NewJWT(secret, jwksFile, issuer, audience string) (*JWT, error) {
+	j := &JWT{secret: []byte(secret)}
+	methods := []string{}
+
+	if secret != "" {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+
+	if jwksFile != "" {
+		keys, err := loadJWKS(jwksFile)
+		if err != nil {
+			return nil, fmt.Errorf("jwks %s: %w", jwksFile, err)
+		}
+		j.keys = keys
+		methods = append(methods, jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg())
+	}
+
+	if len(methods) == 0 {
+		return nil, ErrNoJWTKeys
+	}
+
+	opts := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired()}
+	if issuer != "" {
+		opts = append(opts, jwt.WithIssuer(issuer))
+	}
+	if audience != "" {
+		opts = append(opts, jwt.WithAudience(audience))
+	}
+	j.parser = jwt.NewParser(opts...)
+
+	return j, nil
+}
+
+func (j *JWT) Authenticate(r *http.Request) (Principal, error) {
+	h := r.Header.Get("Authorization")
+	if !strings.HasPrefix(h, bearerScheme) {
+		return Principal{}, ErrNoCredentials
+	}
+
+	var claims jwtClaims
+
+	if _, err := j.parser.ParseWithClaims(strings.TrimPrefix(h, bearerScheme), &claims, j.key); err != nil {
+		return Principal{}, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+
+	if claims.Subject == "" {
+		return Principal{}, fmt.Errorf("%w: token has no subject", ErrInvalidCredentials)
+	}
+
+	return Principal{Name: claims.Subject, Roles: claims.Roles, PersonID: claims.PersonID}, nil
+}
+
+func (j *JWT) key(token *jwt.Token) (any, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		return j.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+
+	key, ok := j.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	return key, nil
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// loadJWKS reads RSA and P-256 public keys by kid. Other key types are skipped.
+func loadJWKS(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]any, len(set.Keys))
+
+	for _, k := range set.Keys {
+		switch {
+		case k.Kty == "RSA":
+			n, err := decodeBigInt(k.N)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", k.Kid, err)
+			}
+			e, err := decodeBigInt(k.E)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", k.Kid, err)
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		case k.Kty == "EC" && k.Crv == "P-256":
+			x, err := decodeBigInt(k.X)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", k.Kid, err)
+			}
+			y, err := decodeBigInt(k.Y)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", k.Kid, err)
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no usable keys")
+	}
+
+	return keys, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,79 @@
+package auth
+
+import "context"
+
+const Anonymous = "anonymous"
+
+const (
+	// RoleAdmin has every other role.
+	RoleAdmin = "admin"
+	// RoleManager sees the persons and time reports of their teams.
+	RoleManager = "manager"
+	// RoleEmployee tracks and sees their own time.
+	RoleEmployee = "employee"
+	// RolePersonalData sees full passport numbers and addresses instead of masked ones.
+	RolePersonalData = "personal_data"
+)
+
+// Roles lists the roles that can be given to API keys and tokens.
+var Roles = []string{RoleAdmin, RoleManager, RoleEmployee, RolePersonalData}
+
+func IsRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Principal is the caller of a request. PersonID links the caller to their person, 0 when there is none.
+type Principal struct {
+	Name     string   `json:"name"`
+	Roles    []string `json:"roles,omitempty"`
+	PersonID int64    `json:"person_id,omitempty"`
+}
+
+// IsPerson tells whether the principal is the person id.
+func (p Principal) IsPerson(id int64) bool {
+	return p.PersonID != 0 && p.PersonID == id
+}
+
+func (p Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role || r == RoleAdmin {
+			return true
+		}
+	}
+
+	return false
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+
+	return p, ok
+}
+
+// Actor returns the name recorded in history and audit logs for the caller.
+func Actor(ctx context.Context) string {
+	if p, ok := FromContext(ctx); ok && p.Name != "" {
+		return p.Name
+	}
+
+	return Anonymous
+}
+
+// HasRole tells whether the caller in ctx has role. Anonymous callers have no roles.
+func HasRole(ctx context.Context, role string) bool {
+	p, ok := FromContext(ctx)
+
+	return ok && p.HasRole(role)
+}
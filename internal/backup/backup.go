@@ -0,0 +1,283 @@
+package backup
+
+import (
+	"bufio"
+	"effective_mobile_testing/internal/db"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Version of the archive format. Bump it when records change shape and keep Restore able to read older versions.
+const Version = 6
+
+const (
+	kindHeader  = "header"
+	kindPerson  = "person"
+	kindTask    = "task"
+	kindHistory = "history"
+
+	kindSequence = "sequence"
+)
+
+const (
+	selectPersonsQuery = `select id, surname, name, patronymic, address, passport_number, deleted_at, anonymized_at, version, merged_into,
+							passport_enc, passport_hash, passport_number_hash from person order by id`
+	selectTasksQuery   = `select id, name, start_tracking, stop_tracking, user_id from task order by id`
+	selectHistoryQuery = `select id, person_id, action, actor, changes, changed_at from person_history order by id`
+	selectSequence     = `select last_value, is_called from %s`
+	checkEmptyQuery    = `select exists(select 1 from person) or exists(select 1 from task)`
+	insertPersonQuery  = `insert into person (id, surname, name, patronymic, address, passport_number, deleted_at, anonymized_at, version, merged_into,
+							passport_enc, passport_hash, passport_number_hash)
+							values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+	insertTaskQuery    = `insert into task (id, name, start_tracking, stop_tracking, user_id) values ($1, $2, $3, $4, $5)`
+	insertHistoryQuery = `insert into person_history (id, person_id, action, actor, changes, changed_at) values ($1, $2, $3, $4, $5, $6)`
+	setSequenceQuery   = `select setval($1, $2, $3)`
+	// the dump reads one snapshot, so tasks and history never refer to persons missing from it
+	snapshotQuery = `set transaction isolation level repeatable read, read only`
+)
+
+// sequences are dumped after the rows so restore can put them back exactly, including is_called.
+var sequences = []string{"person_id_seq", "task_id_seq", "person_history_id_seq"}
+
+var (
+	ErrNotEmpty           = errors.New("database is not empty")
+	ErrUnsupportedVersion = errors.New("unsupported archive version")
+	ErrNoHeader           = errors.New("archive header is missing")
+)
+
+type record struct {
+	Kind string          `json:"kind"`
+	Data json.RawMessage `json:"data"`
+}
+
+type header struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type person struct {
+	ID             int64      `json:"id"`
+	Surname        string     `json:"surname"`
+	Name           string     `json:"name"`
+	Patronymic     *string    `json:"patronymic"`
+	Address        string     `json:"address"`
+	PassportNumber *string    `json:"passport_number"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`    // since version 2
+	AnonymizedAt   *time.Time `json:"anonymized_at,omitempty"` // since version 2
+	Version        int64      `json:"version,omitempty"`       // since version 4
+	MergedInto     *int64     `json:"merged_into,omitempty"`   // since version 5
+	// since version 6 passports are dumped encrypted, PassportNumber is only set for rows not encrypted yet
+	PassportEnc        *string `json:"passport_enc,omitempty"`
+	PassportHash       *string `json:"passport_hash,omitempty"`
+	PassportNumberHash *string `json:"passport_number_hash,omitempty"`
+}
+
+type task struct {
+	ID            int64      `json:"id"`
+	Name          string     `json:"name"`
+	StartTracking *time.Time `json:"start_tracking"`
+	StopTracking  *time.Time `json:"stop_tracking"`
+	UserID        int64      `json:"user_id"`
+}
+
+// history records exist since version 3.
+type history struct {
+	ID        int64           `json:"id"`
+	PersonID  int64           `json:"person_id"`
+	Action    string          `json:"action"`
+	Actor     string          `json:"actor"`
+	Changes   json.RawMessage `json:"changes"`
+	ChangedAt time.Time       `json:"changed_at"`
+}
+
+type sequence struct {
+	Name     string `json:"name"`
+	Value    int64  `json:"value"`
+	IsCalled bool   `json:"is_called"`
+}
+
+// Dump writes every person and task row to w as JSON lines, one record per line.
+// All rows come from one snapshot. Sequences aren't transactional, they may be ahead of the rows.
+func Dump(db db.DB, w io.Writer) error {
+	tx := db.MustBegin()
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(snapshotQuery); err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+
+	write := func(kind string, v any) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		return enc.Encode(record{Kind: kind, Data: data})
+	}
+
+	if err := write(kindHeader, header{Version: Version, CreatedAt: time.Now().UTC()}); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(selectPersonsQuery)
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		var p person
+		if err := rows.Scan(&p.ID, &p.Surname, &p.Name, &p.Patronymic, &p.Address, &p.PassportNumber, &p.DeletedAt, &p.AnonymizedAt, &p.Version, &p.MergedInto,
+			&p.PassportEnc, &p.PassportHash, &p.PassportNumberHash); err != nil {
+			rows.Close()
+			return err
+		}
+		if err := write(kindPerson, p); err != nil {
+			rows.Close()
+			return err
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = tx.Query(selectTasksQuery)
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		var t task
+		if err := rows.Scan(&t.ID, &t.Name, &t.StartTracking, &t.StopTracking, &t.UserID); err != nil {
+			rows.Close()
+			return err
+		}
+		if err := write(kindTask, t); err != nil {
+			rows.Close()
+			return err
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = tx.Query(selectHistoryQuery)
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		var h history
+		var changes []byte
+		if err := rows.Scan(&h.ID, &h.PersonID, &h.Action, &h.Actor, &changes, &h.ChangedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		h.Changes = changes
+		if err := write(kindHistory, h); err != nil {
+			rows.Close()
+			return err
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, name := range sequences {
+		seq := sequence{Name: name}
+		if err := tx.QueryRowx(fmt.Sprintf(selectSequence, name)).Scan(&seq.Value, &seq.IsCalled); err != nil {
+			return err
+		}
+		if err := write(kindSequence, seq); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// Restore loads an archive made by Dump into an empty database in a single transaction.
+func Restore(db db.DB, r io.Reader) error {
+	var notEmpty bool
+	if err := db.QueryRowx(checkEmptyQuery).Scan(&notEmpty); err != nil {
+		return err
+	}
+	if notEmpty {
+		return ErrNotEmpty
+	}
+
+	tx := db.MustBegin()
+	defer tx.Rollback()
+
+	dec := json.NewDecoder(bufio.NewReader(r))
+	seenHeader := false
+
+	for line := 1; ; line++ {
+		var rec record
+		if err := dec.Decode(&rec); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if !seenHeader && rec.Kind != kindHeader {
+			return ErrNoHeader
+		}
+
+		var err error
+
+		switch rec.Kind {
+		case kindHeader:
+			var h header
+			if err = json.Unmarshal(rec.Data, &h); err == nil && (h.Version < 1 || h.Version > Version) {
+				err = fmt.Errorf("%w: %d", ErrUnsupportedVersion, h.Version)
+			}
+			seenHeader = true
+		case kindPerson:
+			var p person
+			if err = json.Unmarshal(rec.Data, &p); err == nil {
+				if p.Version == 0 {
+					p.Version = 1
+				}
+				_, err = tx.Exec(insertPersonQuery, p.ID, p.Surname, p.Name, p.Patronymic, p.Address, p.PassportNumber, p.DeletedAt, p.AnonymizedAt, p.Version, p.MergedInto,
+					p.PassportEnc, p.PassportHash, p.PassportNumberHash)
+			}
+		case kindTask:
+			var t task
+			if err = json.Unmarshal(rec.Data, &t); err == nil {
+				_, err = tx.Exec(insertTaskQuery, t.ID, t.Name, t.StartTracking, t.StopTracking, t.UserID)
+			}
+		case kindHistory:
+			var h history
+			if err = json.Unmarshal(rec.Data, &h); err == nil {
+				_, err = tx.Exec(insertHistoryQuery, h.ID, h.PersonID, h.Action, h.Actor, []byte(h.Changes), h.ChangedAt)
+			}
+		case kindSequence:
+			var s sequence
+			if err = json.Unmarshal(rec.Data, &s); err == nil {
+				_, err = tx.Exec(setSequenceQuery, s.Name, s.Value, s.IsCalled)
+			}
+		default:
+			err = fmt.Errorf("unknown record kind %q", rec.Kind)
+		}
+
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+
+	if !seenHeader {
+		return ErrNoHeader
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"effective_mobile_testing/internal/auth"
+	"effective_mobile_testing/internal/model"
+	"effective_mobile_testing/internal/service/repository"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary      Access audit
+// @Description  who read personal data through /users/, /user/{id} and exports, newest first. Admin only
+// @Tags         audit
+// @Produce      json
+// @Param        actor     query string false "Caller name, e.g. apikey:reports"
+// @Param        action    query string false "users.list, user.detail or user.export"
+// @Param        person_id query int    false "Records that returned this user"
+// @Param        from      query string false "Since, YYYY-MM-DD or RFC 3339"
+// @Param        to        query string false "Before, YYYY-MM-DD or RFC 3339"
+// @Param        limit     query int    false "Page size, capped by PAGE_MAX_SIZE"
+// @Param        cursor    query string false "next_cursor from the previous page"
+// @Param        total     query bool   false "Count all matching records"
+// @Router       /audit/access/ [get]
+func (h *Handlers) GetAccessAudit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "getAccessAudit"
+
+		filter := model.AccessAuditFilter{
+			Actor:  c.Query("actor"),
+			Action: c.Query("action"),
+		}
+
+		if s := c.Query("person_id"); s != "" {
+			personID, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				slog.Error(fmt.Sprintf("%s error convertion: %v", handler, err))
+				c.JSON(http.StatusBadRequest, gin.H{"error": "status bad request"})
+				return
+			}
+			filter.PersonID = personID
+		}
+
+		for _, p := range []struct {
+			name string
+			dst  **time.Time
+		}{
+			{"from", &filter.From},
+			{"to", &filter.To},
+		} {
+			if s := c.Query(p.name); s != "" {
+				t, err := parsePeriod(s, time.Time{})
+				if err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": p.name + " must be YYYY-MM-DD or RFC 3339"})
+					return
+				}
+				*p.dst = &t
+			}
+		}
+
+		page, err := parsePage(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		filter.Cursor = page.cursor
+		filter.Limit = page.limit
+		filter.WithTotal = page.withTotal
+
+		entries, err := h.service.GetAccessAudit(c.Request.Context(), filter)
+		if err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, repository.ErrInvalidCursor) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			slog.Error(fmt.Sprintf("%s error get access audit: %v", handler, err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, entries)
+	}
+}
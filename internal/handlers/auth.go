@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"effective_mobile_testing/internal/auth"
+	"effective_mobile_testing/internal/model"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyInterface interface {
+	Create(ctx context.Context, req model.CreateAPIKeyRequest) (*model.CreatedAPIKey, error)
+	List(ctx context.Context) ([]model.APIKey, error)
+	Revoke(ctx context.Context, id int64) error
+}
+
+// Authenticate resolves the caller with the authenticator and puts the principal into the
+// request context for handlers and services. Requests without valid credentials get 401.
+func Authenticate(authenticator auth.Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "authenticate"
+
+		principal, err := authenticator.Authenticate(c.Request)
+		if err != nil {
+			if errors.Is(err, auth.ErrNoCredentials) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+				return
+			}
+			if errors.Is(err, auth.ErrInvalidCredentials) {
+				slog.Debug(fmt.Sprintf("%s rejected credentials: %v", handler, err))
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+				return
+			}
+			slog.Error(fmt.Sprintf("%s error authenticate: %v", handler, err))
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+		c.Next()
+	}
+}
+
+// @Summary      Create API key
+// @Description  create an API key, admin only. The key is returned only here
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param  		 input body model.CreateAPIKeyRequest true "api key"
+// @Router		 /apikeys/ [post]
+func (h *Handlers) CreateAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "createAPIKey"
+
+		var req model.CreateAPIKeyRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			slog.Error(fmt.Sprintf("%s error with binding request:%v", handler, err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status bad request"})
+			return
+		}
+
+		key, err := h.keys.Create(c.Request.Context(), req)
+		if err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "admin role required"})
+				return
+			}
+			if errors.Is(err, auth.ErrInvalidKey) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			slog.Error(fmt.Sprintf("%s error create api key: %v", handler, err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, key)
+		slog.Debug(fmt.Sprintf("%s api key created", handler))
+	}
+}
+
+// @Summary      List API keys
+// @Description  list API keys with their prefixes, admin only
+// @Tags         auth
+// @Produce      json
+// @Router		 /apikeys/ [get]
+func (h *Handlers) GetAPIKeys() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "getAPIKeys"
+
+		keys, err := h.keys.List(c.Request.Context())
+		if err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "admin role required"})
+				return
+			}
+			slog.Error(fmt.Sprintf("%s error get api keys: %v", handler, err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+	}
+}
+
+// @Summary      Revoke API key
+// @Description  revoke an API key, admin only
+// @Tags         auth
+// @Produce      json
+// @Param  		 id query string true "API key ID"
+// @Router		 /apikeys/ [delete]
+func (h *Handlers) RevokeAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "revokeAPIKey"
+
+		id, err := strconv.ParseInt(c.Query("id"), 10, 64)
+		if err != nil {
+			slog.Error(fmt.Sprintf("%s error convertion: %v", handler, err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status bad request"})
+			return
+		}
+
+		if err := h.keys.Revoke(c.Request.Context(), id); err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "admin role required"})
+				return
+			}
+			if errors.Is(err, auth.ErrKeyNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "api key not found"})
+				return
+			}
+			slog.Error(fmt.Sprintf("%s error revoke api key: %v", handler, err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"status": "api key revoked"})
+		slog.Debug(fmt.Sprintf("%s api key revoked", handler))
+	}
+}
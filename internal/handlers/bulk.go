@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"effective_mobile_testing/internal/auth"
+	"effective_mobile_testing/internal/model"
+	"effective_mobile_testing/internal/service"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxBulkRows = 1000
+
+var errTooManyRows = fmt.Errorf("too many rows, max %d", maxBulkRows)
+
+// @Summary      Bulk create users
+// @Description  create users from a list of passport numbers as an async job. Accepts JSON, text/csv body or multipart "file" with one passport per row
+// @Tags         users
+// @Accept       json
+// @Accept       text/csv
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param  		 input body model.BulkCreateRequest false "passport numbers"
+// @Router		 /users/bulk/ [post]
+func (h *Handlers) BulkCreateUsers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "bulkCreateUsers"
+
+		passports, err := readBulkPassports(c)
+		if err != nil {
+			slog.Error(fmt.Sprintf("%s error with reading request:%v", handler, err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		job, err := h.service.StartBulkCreate(c.Request.Context(), passports)
+		if err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, service.ErrEmptyBulk) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			slog.Error(fmt.Sprintf("%s error start bulk job: %v", handler, err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		maskBulkJob(c, job)
+		c.JSON(http.StatusAccepted, job)
+		slog.Debug(fmt.Sprintf("%s bulk job %s started", handler, job.ID))
+	}
+}
+
+// @Summary      Bulk job status
+// @Description  get per-row results of a bulk create job
+// @Tags         users
+// @Produce      json
+// @Param  		 id query string true "Job ID"
+// @Router		 /users/bulk/ [get]
+func (h *Handlers) GetBulkJob() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "getBulkJob"
+
+		job, err := h.service.GetBulkJob(c.Request.Context(), c.Query("id"))
+		if err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, service.ErrJobNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+				return
+			}
+			slog.Error(fmt.Sprintf("%s error get bulk job: %v", handler, err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		maskBulkJob(c, job)
+		c.JSON(http.StatusOK, job)
+	}
+}
+
+func readBulkPassports(c *gin.Context) ([]string, error) {
+	switch c.ContentType() {
+	case "text/csv":
+		return readCSVPassports(c.Request.Body)
+	case "multipart/form-data":
+		fh, err := c.FormFile("file")
+		if err != nil {
+			return nil, err
+		}
+
+		f, err := fh.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		return readCSVPassports(f)
+	default:
+		var req model.BulkCreateRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			return nil, err
+		}
+		if len(req.PassportNumbers) > maxBulkRows {
+			return nil, errTooManyRows
+		}
+
+		passports := make([]string, 0, len(req.PassportNumbers))
+		for _, p := range req.PassportNumbers {
+			if p = strings.TrimSpace(p); p != "" {
+				passports = append(passports, p)
+			}
+		}
+
+		return passports, nil
+	}
+}
+
+// passportColumns are the header names of the passport column, compared case-insensitively.
+var passportColumns = []string{"passport", "passport_number", "passport number", "passportnumber"}
+
+// readCSVPassports reads one passport per row. The first row is a header when one of its
+// fields is a passport column name, the passports are then taken from that column, otherwise
+// the first row is data and the passports are taken from the first column.
+func readCSVPassports(r io.Reader) ([]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var passports []string
+	column := 0
+
+	for line := 0; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if line == 0 {
+			if i, ok := headerColumn(record); ok {
+				column = i
+				continue
+			}
+		}
+
+		if column >= len(record) {
+			continue
+		}
+
+		p := strings.TrimSpace(record[column])
+		if p == "" {
+			continue
+		}
+
+		if len(passports) == maxBulkRows {
+			return nil, errTooManyRows
+		}
+		passports = append(passports, p)
+	}
+
+	return passports, nil
+}
+
+func headerColumn(record []string) (int, bool) {
+	for i, field := range record {
+		field = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(field, "\ufeff")))
+		if slices.Contains(passportColumns, field) {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadCSVPassports(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+		want []string
+	}{
+		{"no header", "1234 567890\n4321 098765\n", []string{"1234 567890", "4321 098765"}},
+		{"header", "passport\n1234 567890\n", []string{"1234 567890"}},
+		{"header with bom and case", "\ufeffPassport_Number\n1234 567890\n", []string{"1234 567890"}},
+		{"passport column not first", "name,passport number\nИван,1234 567890\nПётр,\n", []string{"1234 567890"}},
+		{"passport-like first row is data", "passports\n1234 567890\n", []string{"passports", "1234 567890"}},
+		{"empty rows skipped", "1234 567890\n\n \n", []string{"1234 567890"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readCSVPassports(strings.NewReader(tt.csv))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"effective_mobile_testing/internal/model"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errMissingIfMatch = errors.New("If-Match header with the user ETag is required")
+	errInvalidIfMatch = errors.New("If-Match must be an ETag returned for the user or *")
+)
+
+// etag is the strong entity tag of a user version.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+func setETag(c *gin.Context, user *model.User) {
+	if user != nil && user.Version != 0 {
+		c.Header("ETag", etag(user.Version))
+	}
+}
+
+// parseIfMatch returns the user version the client expects. "*" matches any version and gives 0.
+func parseIfMatch(c *gin.Context) (int64, error) {
+	value := strings.TrimSpace(c.GetHeader("If-Match"))
+	if value == "" {
+		return 0, errMissingIfMatch
+	}
+	if value == "*" {
+		return 0, nil
+	}
+
+	if len(value) < 3 || value[0] != '"' || value[len(value)-1] != '"' {
+		return 0, errInvalidIfMatch
+	}
+
+	version, err := strconv.ParseInt(value[1:len(value)-1], 10, 64)
+	if err != nil || version < 1 {
+		return 0, errInvalidIfMatch
+	}
+
+	return version, nil
+}
+
+func ifMatchStatus(err error) int {
+	if errors.Is(err, errMissingIfMatch) {
+		return http.StatusPreconditionRequired
+	}
+
+	return http.StatusBadRequest
+}
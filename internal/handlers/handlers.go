@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 
+	"effective_mobile_testing/internal/auth"
 	"effective_mobile_testing/internal/model"
 	"effective_mobile_testing/internal/service/repository"
 	"effective_mobile_testing/internal/validators"
@@ -17,22 +20,38 @@ import (
 )
 
 type Handlers struct {
-	service HandlerInterface
+	service  HandlerInterface
+	webhooks WebhookInterface
+	keys     APIKeyInterface
+	teams    TeamInterface
 }
 
 type HandlerInterface interface {
-	CreateUser(passportNumber string, user model.UserFromAPI) (*model.User, error)
-	GetUserData(passportSerie, passportNumber string) (model.UserFromAPI, error)
-	StartTracking(req model.RequestStartTracking) error
-	StopTracking(req model.RequestStopTracking) error
-	GetLaborCosts(userID int64) ([]model.ResponseLobarCost, error)
-	GetUserByFilters(limit, offset int, user model.User) (*[]model.User, error)
-	DeleteUser(id int64) error
-	UpdateUser(id int64, user model.UserUpdateRequest) (*model.User, error)
+	CreateUser(ctx context.Context, passportNumber string, user model.UserFromAPI) (*model.User, error)
+	GetUserData(ctx context.Context, passportSerie, passportNumber string) (model.UserFromAPI, error)
+	GetRegistryCacheStats(ctx context.Context) (*model.RegistryCacheStats, error)
+	StartTracking(ctx context.Context, req model.RequestStartTracking) error
+	StopTracking(ctx context.Context, req model.RequestStopTracking) error
+	GetLaborCosts(ctx context.Context, filter model.LaborCostFilter) (*model.LaborCostPage, error)
+	GetUserByFilters(ctx context.Context, filter model.UserFilter) (*model.UserPage, error)
+	DeleteUser(ctx context.Context, id, version int64) error
+	UpdateUser(ctx context.Context, id, version int64, patch model.UserPatch) (*model.User, error)
+	StartBulkCreate(ctx context.Context, passportNumbers []string) (*model.BulkJob, error)
+	GetBulkJob(ctx context.Context, id string) (*model.BulkJob, error)
+	RestoreUser(ctx context.Context, id int64) (*model.User, error)
+	GetUserDetail(ctx context.Context, id int64) (*model.UserDetail, error)
+	FindDuplicates(ctx context.Context, id int64) ([]model.DuplicateCandidate, error)
+	MergeUsers(ctx context.Context, survivorID, duplicateID int64) (*model.User, error)
+	ExportUser(ctx context.Context, id int64) (*model.PersonalDataExport, error)
+	EraseUser(ctx context.Context, id int64) error
+	SyncUser(ctx context.Context, id int64, apply bool) (*model.SyncResult, error)
+	GetSyncReport(ctx context.Context) (*model.SyncReport, error)
+	GetUserHistory(ctx context.Context, filter model.HistoryFilter) (*model.HistoryPage, error)
+	GetAccessAudit(ctx context.Context, filter model.AccessAuditFilter) (*model.AccessAuditPage, error)
 }
 
-func NewHandlers(service HandlerInterface) *Handlers {
-	return &Handlers{service}
+func NewHandlers(service HandlerInterface, webhooks WebhookInterface, keys APIKeyInterface, teams TeamInterface) *Handlers {
+	return &Handlers{service: service, webhooks: webhooks, keys: keys, teams: teams}
 }
 
 // @Summary      Create User
@@ -62,8 +81,15 @@ func (h *Handlers) CreateUser() gin.HandlerFunc {
 			return
 		}
 
-		userFromAPI, err := h.service.GetUserData(passport[0], passport[1])
+		userFromAPI, err := h.service.GetUserData(c.Request.Context(), passport[0], passport[1])
 		if err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if writeRegistryError(c, handler, err) {
+				return
+			}
 			slog.Error("Error getting user from API:", slog.String("error", err.Error()))
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
 			return
@@ -71,9 +97,13 @@ func (h *Handlers) CreateUser() gin.HandlerFunc {
 
 		totalPassport := passport[0] + " " + passport[1]
 
-		user, err := h.service.CreateUser(totalPassport, userFromAPI)
+		user, err := h.service.CreateUser(c.Request.Context(), totalPassport, userFromAPI)
 		if err != nil {
-			if errors.As(err, &repository.ErrUserExists) {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, repository.ErrUserExists) {
 				slog.Error(fmt.Sprintf("%s error create user: %v", handler, err))
 				c.JSON(http.StatusBadRequest, gin.H{"error": "user already exists"})
 				return
@@ -84,7 +114,8 @@ func (h *Handlers) CreateUser() gin.HandlerFunc {
 			return
 		}
 
-		c.JSON(http.StatusOK, user)
+		setETag(c, user)
+		c.JSON(http.StatusOK, maskUser(c, user))
 		slog.Debug(fmt.Sprintf("%s created user", handler))
 	}
 }
@@ -108,8 +139,12 @@ func (h *Handlers) StartTracking() gin.HandlerFunc {
 			return
 		}
 
-		if err := h.service.StartTracking(req); err != nil {
-			if errors.As(err, &repository.ErrUserNotFound) {
+		if err := h.service.StartTracking(c.Request.Context(), req); err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, repository.ErrUserNotFound) {
 				slog.Error(fmt.Sprintf("%s error start tracking: %v", handler, err))
 				c.JSON(http.StatusBadRequest, gin.H{"error": "status bad request"})
 				return
@@ -143,7 +178,11 @@ func (h *Handlers) StopTracking() gin.HandlerFunc {
 			return
 		}
 
-		if err := h.service.StopTracking(req); err != nil {
+		if err := h.service.StopTracking(c.Request.Context(), req); err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
 			if err != sql.ErrNoRows {
 				slog.Error("error stop tracking:", slog.String("error", err.Error()))
 				c.JSON(http.StatusBadRequest, gin.H{"error": "status bad request"})
@@ -166,6 +205,9 @@ func (h *Handlers) StopTracking() gin.HandlerFunc {
 // @Accept       json
 // @Produce      json
 // @Param  		 id query string true "User ID"
+// @Param        limit  query int    false "Page size, capped by PAGE_MAX_SIZE"
+// @Param        cursor query string false "next_cursor from the previous page"
+// @Param        total  query bool   false "Count all tasks"
 // @Router		 /user/get-costs/ [get]
 func (h *Handlers) GetLaborCosts() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -180,20 +222,38 @@ func (h *Handlers) GetLaborCosts() gin.HandlerFunc {
 			return
 		}
 
-		costs, err := h.service.GetLaborCosts(int64(id))
+		page, err := parsePage(c)
 		if err != nil {
-			if err != sql.ErrNoRows {
-				slog.Error(fmt.Sprintf("%s error get labor costs: %v", handler, err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		costs, err := h.service.GetLaborCosts(c.Request.Context(), model.LaborCostFilter{
+			UserID:    int64(id),
+			Cursor:    page.cursor,
+			Limit:     page.limit,
+			WithTotal: page.withTotal,
+		})
+		if err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, repository.ErrInvalidCursor) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, repository.ErrUserNotFound) {
 				c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
 				return
 			}
 
-			slog.Error("error get labor costs:", slog.String("error", err.Error()))
+			slog.Error(fmt.Sprintf("%s error get labor costs: %v", handler, err))
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"costs": costs})
+		c.JSON(http.StatusOK, costs)
 		slog.Debug(fmt.Sprintf("%s labor costs finished", handler))
 	}
 }
@@ -209,8 +269,18 @@ func (h *Handlers) GetLaborCosts() gin.HandlerFunc {
 // @Param        patronymic     query string false "Patronymic"
 // @Param        address        query string false "Address"
 // @Param        passport_number query string false "Passport Number"
-// @Param        limit          query int    false "Limit"
-// @Param        offset         query int    false "Offset"
+// @Param        match          query string false "Default match mode for text fields" Enums(exact, prefix, contains, fuzzy)
+// @Param        surname_match  query string false "Surname match mode" Enums(exact, prefix, contains, fuzzy)
+// @Param        name_match     query string false "Name match mode" Enums(exact, prefix, contains, fuzzy)
+// @Param        patronymic_match query string false "Patronymic match mode" Enums(exact, prefix, contains, fuzzy)
+// @Param        address_match  query string false "Address match mode" Enums(exact, prefix, contains, fuzzy)
+// @Param        q              query string false "Search in surname, name, patronymic and address"
+// @Param        sort           query string false "Comma separated columns, - for descending, e.g. surname,-id"
+// @Param        limit          query int    false "Page size, capped by PAGE_MAX_SIZE"
+// @Param        cursor         query string false "next_cursor from the previous page"
+// @Param        total          query bool   false "Count all matching users"
+// @Param        offset         query int    false "Rows to skip, can't be combined with cursor"
+// @Param        include_deleted query bool  false "Include soft deleted users"
 // @Router       /users/ [get]
 func (h *Handlers) GetUserByFilters() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -218,7 +288,6 @@ func (h *Handlers) GetUserByFilters() gin.HandlerFunc {
 
 		var (
 			idInt     int
-			limitInt  int
 			offsetInt int
 		)
 
@@ -233,62 +302,104 @@ func (h *Handlers) GetUserByFilters() gin.HandlerFunc {
 			idInt = id
 		}
 
-		surname := c.Query("surname")
-		name := c.Query("name")
-		patronymic := c.Query("patronymic")
-		address := c.Query("address")
-		passportNumber := c.Query("passport_number")
+		match := c.DefaultQuery("match", model.MatchExact)
+		if !validators.IsMatchMode(match) {
+			slog.Error(fmt.Sprintf("%s unknown match mode %q", handler, match))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown match mode"})
+			return
+		}
 
-		l := c.Query("limit")
-		if l != "" {
-			limit, err := strconv.Atoi(l)
-			if err != nil {
-				slog.Error(fmt.Sprintf("%s error covertion: %v", handler, err))
-				c.JSON(http.StatusBadRequest, gin.H{"error": "status bad request"})
+		var filter model.UserFilter
+
+		for field, f := range map[string]*model.FieldFilter{
+			"surname":    &filter.Surname,
+			"name":       &filter.Name,
+			"patronymic": &filter.Patronymic,
+			"address":    &filter.Address,
+		} {
+			mode := c.DefaultQuery(field+"_match", match)
+			if !validators.IsMatchMode(mode) {
+				slog.Error(fmt.Sprintf("%s unknown %s match mode %q", handler, field, mode))
+				c.JSON(http.StatusBadRequest, gin.H{"error": "unknown match mode"})
 				return
 			}
-			limitInt = limit
+
+			*f = model.FieldFilter{Value: c.Query(field), Mode: mode}
+		}
+
+		filter.PassportNumber = c.Query("passport_number")
+		filter.Query = c.Query("q")
+		filter.Sort = c.Query("sort")
+
+		page, err := parsePage(c)
+		if err != nil {
+			slog.Error(fmt.Sprintf("%s error covertion: %v", handler, err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
+
 		o := c.Query("offset")
 		if o != "" {
 			offset, err := strconv.Atoi(o)
-			if err != nil {
-				slog.Error(fmt.Sprintf("%s error covertion: %v", handler, err))
-				log.Printf("%s error convertion: %v", handler, err)
-				c.JSON(http.StatusBadRequest, gin.H{"error": "status bad request"})
+			if err != nil || offset < 0 || page.cursor != "" {
+				slog.Error(fmt.Sprintf("%s invalid offset %q", handler, o))
+				c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a positive number and can't be used with cursor"})
 				return
 			}
 			offsetInt = offset
 		}
 
-		var user model.User
+		filter.ID = int64(idInt)
+		filter.Limit = page.limit
+		filter.Cursor = page.cursor
+		filter.WithTotal = page.withTotal
+		filter.Offset = offsetInt
 
-		user.ID = int64(idInt)
-		user.Surname = surname
-		user.Name = name
-		user.Patronymic = patronymic
-		user.Address = address
-		user.PassportNumber = passportNumber
+		if s := c.Query("include_deleted"); s != "" {
+			includeDeleted, err := strconv.ParseBool(s)
+			if err != nil {
+				slog.Error(fmt.Sprintf("%s error covertion: %v", handler, err))
+				c.JSON(http.StatusBadRequest, gin.H{"error": "status bad request"})
+				return
+			}
+			filter.IncludeDeleted = includeDeleted
+		}
 
-		users, err := h.service.GetUserByFilters(limitInt, offsetInt, user)
+		users, err := h.service.GetUserByFilters(c.Request.Context(), filter)
 		if err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, repository.ErrInvalidSort) || errors.Is(err, repository.ErrInvalidCursor) {
+				slog.Error(fmt.Sprintf("%s %v", handler, err))
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
 			slog.Error(fmt.Sprintf("%s error get user by filter: %v", handler, err))
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"users": users})
+		// lookup by id gives the ETag to use in If-Match
+		if filter.ID != 0 && len(users.Users) == 1 {
+			setETag(c, &users.Users[0])
+		}
+
+		users.Users = maskUsers(c, users.Users)
+		c.JSON(http.StatusOK, users)
 		slog.Debug(fmt.Sprintf("%s get user by filter finished", handler))
 
 	}
 }
 
 // @Summary      Delete User
-// @Description  delete user data
+// @Description  soft delete user, tasks are kept. The user can be restored until the retention purge
 // @Tags         users
 // @Accept       json
 // @Produce      json
 // @Param  		 id query string true "ID"
+// @Param        If-Match header string true "ETag of the user or *"
 // @Router		 /user/ [delete]
 func (h *Handlers) DeleteUser() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -302,7 +413,25 @@ func (h *Handlers) DeleteUser() gin.HandlerFunc {
 			return
 		}
 
-		if err := h.service.DeleteUser(int64(id)); err != nil {
+		version, err := parseIfMatch(c)
+		if err != nil {
+			c.JSON(ifMatchStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := h.service.DeleteUser(c.Request.Context(), int64(id), version); err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, repository.ErrUserNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+				return
+			}
+			if errors.Is(err, repository.ErrVersionMismatch) {
+				c.JSON(http.StatusPreconditionFailed, gin.H{"error": "user was modified, fetch it again"})
+				return
+			}
 			slog.Error(fmt.Sprintf("%s error deleting user: %v", handler, err))
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "status bad request"})
 			return
@@ -315,12 +444,14 @@ func (h *Handlers) DeleteUser() gin.HandlerFunc {
 }
 
 // @Summary      Update User
-// @Description  update user data
+// @Description  update user data with JSON Merge Patch: omitted fields are kept, null clears patronymic
 // @Tags         users
 // @Accept       json
+// @Accept       application/merge-patch+json
 // @Produce      json
 // @Param        id             query string false "User ID"
-// @Param  		 input body model.UserUpdateRequest false "user udpate data"
+// @Param        If-Match       header string true "ETag of the user or *"
+// @Param  		 input body model.UserPatch false "user update data"
 // @Router       /user/ [patch]
 func (h *Handlers) UpdateUser() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -334,29 +465,191 @@ func (h *Handlers) UpdateUser() gin.HandlerFunc {
 			return
 		}
 
-		var user model.UserUpdateRequest
+		version, err := parseIfMatch(c)
+		if err != nil {
+			c.JSON(ifMatchStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		var patch model.UserPatch
 
-		if err := c.ShouldBindJSON(&user); err != nil {
+		dec := json.NewDecoder(c.Request.Body)
+		dec.DisallowUnknownFields()
+
+		if err := dec.Decode(&patch); err != nil {
 			slog.Error(fmt.Sprintf("%s error binding json request: %v", handler, err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": "body must be a JSON object with user fields"})
 			return
 		}
 
-		updateUser, err := h.service.UpdateUser(int64(id), user)
+		updateUser, err := h.service.UpdateUser(c.Request.Context(), int64(id), version, patch)
 		if err != nil {
-			if err != sql.ErrNoRows {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			switch {
+			case errors.Is(err, validators.ErrInvalidField):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			case errors.Is(err, repository.ErrUserNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			case errors.Is(err, repository.ErrUserExists):
+				c.JSON(http.StatusBadRequest, gin.H{"error": "user with this passport already exists"})
+			case errors.Is(err, repository.ErrVersionMismatch):
+				c.JSON(http.StatusPreconditionFailed, gin.H{"error": "user was modified, fetch it again"})
+			default:
 				slog.Error(fmt.Sprintf("%s error update user: %v", handler, err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			}
+			return
+		}
+
+		setETag(c, updateUser)
+		c.JSON(http.StatusOK, maskUser(c, updateUser))
+		slog.Debug(fmt.Sprintf("%s user updated success", handler))
+
+	}
+}
+
+// @Summary      Restore User
+// @Description  restore soft deleted user
+// @Tags         users
+// @Produce      json
+// @Param  		 id query string true "ID"
+// @Router		 /user/restore/ [post]
+func (h *Handlers) RestoreUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "restoreUser"
+
+		id, err := strconv.ParseInt(c.Query("id"), 10, 64)
+		if err != nil {
+			slog.Error(fmt.Sprintf("%s error convertion: %v", handler, err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status bad request"})
+			return
+		}
+
+		user, err := h.service.RestoreUser(c.Request.Context(), id)
+		if err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, repository.ErrUserNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "deleted user not found"})
+				return
+			}
+			if errors.Is(err, repository.ErrUserExists) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "user with this passport already exists"})
+				return
+			}
+			slog.Error(fmt.Sprintf("%s error restoring user: %v", handler, err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		setETag(c, user)
+		c.JSON(http.StatusOK, maskUser(c, user))
+		slog.Debug(fmt.Sprintf("%s user restored success", handler))
+	}
+}
+
+// @Summary      User detail
+// @Description  user with the running task, minutes tracked today, this week and this month and the recent tasks. Ids of merged users return the survivor
+// @Tags         users
+// @Produce      json
+// @Param        id path int true "User ID"
+// @Router       /user/{id} [get]
+func (h *Handlers) GetUserDetail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "getUserDetail"
+
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			slog.Error(fmt.Sprintf("%s error convertion: %v", handler, err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status bad request"})
+			return
+		}
+
+		detail, err := h.service.GetUserDetail(c.Request.Context(), id)
+		if err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, repository.ErrUserNotFound) {
 				c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
 				return
 			}
+			slog.Error(fmt.Sprintf("%s error get user detail: %v", handler, err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
 
-			slog.Error(fmt.Sprintf("%s error update user: %v", handler, err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "status bad request"})
+		// a merged id resolves to the survivor
+		if detail.User.ID != id {
+			c.Header("Content-Location", fmt.Sprintf("/user/%d", detail.User.ID))
+		}
+
+		setETag(c, &detail.User)
+		detail.User = *maskUser(c, &detail.User)
+		c.JSON(http.StatusOK, detail)
+	}
+}
+
+// @Summary      User history
+// @Description  changes of user data with actor and old/new values, newest first
+// @Tags         users
+// @Produce      json
+// @Param        id     path  int    true  "User ID"
+// @Param        limit  query int    false "Page size, capped by PAGE_MAX_SIZE"
+// @Param        cursor query string false "next_cursor from the previous page"
+// @Param        total  query bool   false "Count all changes"
+// @Router       /user/{id}/history [get]
+func (h *Handlers) GetUserHistory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "getUserHistory"
+
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			slog.Error(fmt.Sprintf("%s error convertion: %v", handler, err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status bad request"})
 			return
 		}
 
-		c.JSON(http.StatusOK, updateUser)
-		slog.Debug(fmt.Sprintf("%s user updated success", handler))
+		page, err := parsePage(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		history, err := h.service.GetUserHistory(c.Request.Context(), model.HistoryFilter{
+			PersonID:  id,
+			Cursor:    page.cursor,
+			Limit:     page.limit,
+			WithTotal: page.withTotal,
+		})
+		if err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, repository.ErrUserNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+				return
+			}
+			if errors.Is(err, repository.ErrInvalidCursor) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			slog.Error(fmt.Sprintf("%s error get user history: %v", handler, err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		for i := range history.History {
+			history.History[i].Changes = maskChanges(c, history.History[i].Changes)
+		}
 
+		c.JSON(http.StatusOK, history)
 	}
 }
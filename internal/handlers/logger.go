@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"effective_mobile_testing/internal/mask"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestLogger logs requests through slog instead of the gin logger. Personal query
+// parameters like passport_number, surname or q are masked by name.
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		path := c.Request.URL.Path
+		if raw := c.Request.URL.RawQuery; raw != "" {
+			path += "?" + mask.Query(raw)
+		}
+
+		slog.Info(fmt.Sprintf("%s %s", c.Request.Method, path),
+			slog.Int("status", c.Writer.Status()),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+		)
+	}
+}
+
+// Recovery answers 500 to a panicking request. Unlike gin.Recovery it doesn't dump the request:
+// headers carry API keys and bodies personal data, only the panic and the stack go to the masked log.
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// the server aborts the response on purpose, let it
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			slog.Error(fmt.Sprintf("panic in %s %s", c.Request.Method, c.Request.URL.Path),
+				slog.String("panic", fmt.Sprint(rec)),
+				slog.String("stack", string(debug.Stack())),
+			)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		}()
+
+		c.Next()
+	}
+}
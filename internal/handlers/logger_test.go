@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"bytes"
+	"effective_mobile_testing/internal/mask"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRecoveryDoesNotLogRequest(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(mask.NewHandler(slog.NewTextHandler(&buf, nil))))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Recovery())
+	r.POST("/user/", func(c *gin.Context) { panic("boom") })
+
+	req := httptest.NewRequest(http.MethodPost, "/user/?passport_number=1234+567890", strings.NewReader(`{"passport_number":"1234 567890"}`))
+	req.Header.Set("X-API-Key", "secret-api-key")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status %d, want 500", w.Code)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "boom") {
+		t.Fatalf("panic not logged: %s", out)
+	}
+	for _, leak := range []string{"secret-api-key", "567890"} {
+		if strings.Contains(out, leak) {
+			t.Errorf("log contains %q: %s", leak, out)
+		}
+	}
+}
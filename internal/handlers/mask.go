@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"effective_mobile_testing/internal/auth"
+	"effective_mobile_testing/internal/mask"
+	"effective_mobile_testing/internal/model"
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+)
+
+// canSeePersonalData tells whether responses may carry full passports and addresses.
+func canSeePersonalData(c *gin.Context) bool {
+	return auth.HasRole(c.Request.Context(), auth.RolePersonalData)
+}
+
+// maskUser returns the user as the caller may see it. The original is not changed.
+func maskUser(c *gin.Context, u *model.User) *model.User {
+	if u == nil || canSeePersonalData(c) {
+		return u
+	}
+
+	masked := *u
+	if masked.PassportNumber != "" {
+		masked.PassportNumber = mask.Passport(masked.PassportNumber)
+	}
+	masked.Address = mask.Address(masked.Address)
+
+	return &masked
+}
+
+func maskUsers(c *gin.Context, users []model.User) []model.User {
+	if canSeePersonalData(c) {
+		return users
+	}
+
+	masked := make([]model.User, len(users))
+	for i := range users {
+		masked[i] = *maskUser(c, &users[i])
+	}
+
+	return masked
+}
+
+// maskChanges masks addresses in field changes. Passports are already masked in history.
+func maskChanges(c *gin.Context, changes map[string]model.FieldChange) map[string]model.FieldChange {
+	change, ok := changes["address"]
+	if !ok || canSeePersonalData(c) {
+		return changes
+	}
+
+	masked := make(map[string]model.FieldChange, len(changes))
+	for k, v := range changes {
+		masked[k] = v
+	}
+	masked["address"] = model.FieldChange{Old: maskOptional(change.Old, mask.Address), New: maskOptional(change.New, mask.Address)}
+
+	return masked
+}
+
+func maskOptional(s *string, f func(string) string) *string {
+	if s == nil {
+		return nil
+	}
+
+	masked := f(*s)
+
+	return &masked
+}
+
+func maskBulkJob(c *gin.Context, job *model.BulkJob) {
+	if canSeePersonalData(c) {
+		return
+	}
+
+	for i := range job.Rows {
+		job.Rows[i].PassportNumber = mask.Passport(job.Rows[i].PassportNumber)
+	}
+}
+
+func maskSyncResult(c *gin.Context, res *model.SyncResult) {
+	res.User = maskUser(c, res.User)
+	res.Changes = maskChanges(c, res.Changes)
+}
+
+// maskPayload masks passport and address fields at any depth of an event payload.
+func maskPayload(c *gin.Context, payload json.RawMessage) json.RawMessage {
+	if canSeePersonalData(c) {
+		return payload
+	}
+
+	var v any
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return payload
+	}
+
+	masked, err := json.Marshal(maskValue(v))
+	if err != nil {
+		return payload
+	}
+
+	return masked
+}
+
+func maskValue(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			s, isString := val.(string)
+			switch {
+			case k == "passport_number" && isString:
+				t[k] = mask.Passport(s)
+			case k == "address" && isString:
+				t[k] = mask.Address(s)
+			default:
+				t[k] = maskValue(val)
+			}
+		}
+	case []any:
+		for i := range t {
+			t[i] = maskValue(t[i])
+		}
+	}
+
+	return v
+}
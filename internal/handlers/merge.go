@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"effective_mobile_testing/internal/auth"
+	"effective_mobile_testing/internal/model"
+	"effective_mobile_testing/internal/service/repository"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary      Duplicate candidates
+// @Description  persons that look like the same human: similar full name, similar address, same passport number
+// @Tags         users
+// @Produce      json
+// @Param        id path int true "User ID"
+// @Router       /user/{id}/duplicates [get]
+func (h *Handlers) GetDuplicates() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "getDuplicates"
+
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			slog.Error(fmt.Sprintf("%s error convertion: %v", handler, err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status bad request"})
+			return
+		}
+
+		duplicates, err := h.service.FindDuplicates(c.Request.Context(), id)
+		if err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, repository.ErrUserNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+				return
+			}
+			slog.Error(fmt.Sprintf("%s error find duplicates: %v", handler, err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		for i := range duplicates {
+			duplicates[i].User = *maskUser(c, &duplicates[i].User)
+		}
+
+		c.JSON(http.StatusOK, duplicates)
+	}
+}
+
+// @Summary      Merge users
+// @Description  move tasks of the duplicate to the survivor and delete the duplicate. The duplicate id keeps resolving to the survivor
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        input body model.MergeUsersRequest true "survivor and duplicate ids"
+// @Router       /users/merge/ [post]
+func (h *Handlers) MergeUsers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "mergeUsers"
+
+		var req model.MergeUsersRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			slog.Error(fmt.Sprintf("%s error with binding request:%v", handler, err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status bad request"})
+			return
+		}
+
+		user, err := h.service.MergeUsers(c.Request.Context(), req.SurvivorID, req.DuplicateID)
+		if err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			switch {
+			case errors.Is(err, repository.ErrSameUser):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			case errors.Is(err, repository.ErrUserNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			case errors.Is(err, repository.ErrMembershipOverlaps):
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			default:
+				slog.Error(fmt.Sprintf("%s error merge users: %v", handler, err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			}
+			return
+		}
+
+		setETag(c, user)
+		c.JSON(http.StatusOK, maskUser(c, user))
+		slog.Debug(fmt.Sprintf("%s user %d merged into %d", handler, req.DuplicateID, req.SurvivorID))
+	}
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errInvalidPage = errors.New("limit must be a positive number and total a boolean")
+
+type pageParams struct {
+	limit     int
+	cursor    string
+	withTotal bool
+}
+
+// parsePage reads limit, cursor and total query parameters shared by list endpoints.
+// The page size itself is capped by the service.
+func parsePage(c *gin.Context) (pageParams, error) {
+	p := pageParams{cursor: c.Query("cursor")}
+
+	if s := c.Query("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit < 0 {
+			return p, errInvalidPage
+		}
+		p.limit = limit
+	}
+
+	if s := c.Query("total"); s != "" {
+		withTotal, err := strconv.ParseBool(s)
+		if err != nil {
+			return p, errInvalidPage
+		}
+		p.withTotal = withTotal
+	}
+
+	return p, nil
+}
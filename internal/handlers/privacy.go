@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"effective_mobile_testing/internal/auth"
+	"effective_mobile_testing/internal/model"
+	"effective_mobile_testing/internal/service/repository"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary      Export personal data
+// @Description  person record, all tracked tasks and the change history as JSON or a ZIP with one file per part. The export is recorded in the history
+// @Tags         privacy
+// @Produce      json
+// @Produce      application/zip
+// @Param        id     path  int    true  "User ID"
+// @Param        format query string false "json (default) or zip"
+// @Router       /user/{id}/export [get]
+func (h *Handlers) ExportUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "exportUser"
+
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			slog.Error(fmt.Sprintf("%s error convertion: %v", handler, err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status bad request"})
+			return
+		}
+
+		format := c.DefaultQuery("format", "json")
+		if format != "json" && format != "zip" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or zip"})
+			return
+		}
+
+		export, err := h.service.ExportUser(c.Request.Context(), id)
+		if err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, repository.ErrUserNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+				return
+			}
+			slog.Error(fmt.Sprintf("%s error export user: %v", handler, err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		export.User = *maskUser(c, &export.User)
+		for i := range export.History {
+			export.History[i].Changes = maskChanges(c, export.History[i].Changes)
+		}
+
+		filename := fmt.Sprintf("user-%d-export.%s", id, format)
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+		if format == "json" {
+			c.JSON(http.StatusOK, export)
+			return
+		}
+
+		archive, err := exportZip(export)
+		if err != nil {
+			slog.Error(fmt.Sprintf("%s error build zip: %v", handler, err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		c.Data(http.StatusOK, "application/zip", archive)
+	}
+}
+
+// @Summary      Erase personal data
+// @Description  anonymize the person and persons merged into it, remove personal fields from history and stored events. Tracked time is kept
+// @Tags         privacy
+// @Produce      json
+// @Param        id path int true "User ID"
+// @Router       /user/{id}/erase [post]
+func (h *Handlers) EraseUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "eraseUser"
+
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			slog.Error(fmt.Sprintf("%s error convertion: %v", handler, err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status bad request"})
+			return
+		}
+
+		if err := h.service.EraseUser(c.Request.Context(), id); err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, repository.ErrUserNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+				return
+			}
+			slog.Error(fmt.Sprintf("%s error erase user: %v", handler, err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"status": "personal data erased"})
+	}
+}
+
+func exportZip(export *model.PersonalDataExport) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	for _, f := range []struct {
+		name string
+		data any
+	}{
+		{"person.json", export.User},
+		{"tasks.json", export.Tasks},
+		{"history.json", export.History},
+	} {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: export.ExportedAt})
+		if err != nil {
+			return nil, err
+		}
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"effective_mobile_testing/internal/auth"
+	"effective_mobile_testing/internal/ratelimit"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit limits requests per API key or token subject when it runs after Authenticate,
+// and per client IP when it runs before, where no caller is known yet. A nil limiter lets everything through.
+func RateLimit(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "rateLimit"
+
+		key := "ip:" + c.ClientIP()
+		if p, ok := auth.FromContext(c.Request.Context()); ok && p.Name != "" {
+			key = "principal:" + p.Name
+		}
+
+		ok, wait := limiter.Allow(key)
+		if !ok {
+			slog.Debug(fmt.Sprintf("%s %s is limited on %s", handler, key, c.FullPath()))
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"effective_mobile_testing/internal/auth"
+	"effective_mobile_testing/internal/registry"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary      Registry cache stats
+// @Description  passport registry cache hits and misses counted by this instance since start. Admin only
+// @Tags         registry
+// @Produce      json
+// @Router       /registry/cache/ [get]
+func (h *Handlers) GetRegistryCacheStats() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "getRegistryCacheStats"
+
+		stats, err := h.service.GetRegistryCacheStats(c.Request.Context())
+		if err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			slog.Error(fmt.Sprintf("%s error get registry cache stats: %v", handler, err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, stats)
+	}
+}
+
+// writeRegistryError answers with the status matching a passport registry failure.
+// It returns false when err is not a registry failure and the caller must handle it.
+func writeRegistryError(c *gin.Context, handler string, err error) bool {
+	var unavailable *registry.UnavailableError
+
+	switch {
+	case errors.Is(err, registry.ErrPassportNotFound):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "passport not found in the registry"})
+	case errors.Is(err, registry.ErrInvalidRequest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "passport rejected by the registry"})
+	case errors.As(err, &unavailable):
+		slog.Error(fmt.Sprintf("%s registry is unavailable: %v", handler, err))
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(unavailable.RetryAfter.Seconds()))))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "registry is unavailable, try again later"})
+	case errors.Is(err, registry.ErrUnavailable), errors.Is(err, registry.ErrNotConfigured):
+		slog.Error(fmt.Sprintf("%s registry is unavailable: %v", handler, err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "registry is unavailable"})
+	case errors.Is(err, registry.ErrMalformedResponse):
+		slog.Error(fmt.Sprintf("%s bad registry response: %v", handler, err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "registry returned invalid data"})
+	default:
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"effective_mobile_testing/internal/registry"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeRegistry answers by passport number: a status code, "slow" or "empty" for a person without a name.
+func fakeRegistry(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch n := r.URL.Query().Get("passportNumber"); n {
+		case "slow":
+			time.Sleep(200 * time.Millisecond)
+			fmt.Fprint(w, `{"surname":"Иванов","name":"Иван"}`)
+		case "empty":
+			fmt.Fprint(w, `{"surname":"","name":"Иван"}`)
+		default:
+			code, _ := strconv.Atoi(n)
+			w.WriteHeader(code)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestWriteRegistryError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv := fakeRegistry(t)
+
+	t.Setenv("ANOTHER_API_URL", srv.URL)
+	t.Setenv("REGISTRY_TIMEOUT", "50ms")
+	t.Setenv("REGISTRY_MAX_ATTEMPTS", "2")
+	t.Setenv("REGISTRY_RETRY_DELAY", "1ms")
+	t.Setenv("REGISTRY_BREAKER_THRESHOLD", "100")
+
+	tests := []struct {
+		name       string
+		passport   string
+		status     int
+		retryAfter bool
+	}{
+		{"not found", "404", http.StatusUnprocessableEntity, false},
+		{"unprocessable", "422", http.StatusBadRequest, false},
+		{"bad request", "400", http.StatusBadRequest, false},
+		{"server error", "500", http.StatusServiceUnavailable, true},
+		{"bad gateway", "502", http.StatusServiceUnavailable, true},
+		{"too many requests", "429", http.StatusServiceUnavailable, true},
+		{"unauthorized", "401", http.StatusServiceUnavailable, true},
+		{"timeout", "slow", http.StatusServiceUnavailable, true},
+		{"empty name", "empty", http.StatusBadGateway, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := registry.NewClient().GetPerson(context.Background(), "1234", tt.passport)
+			assertRegistryResponse(t, err, tt.status, tt.retryAfter)
+		})
+	}
+}
+
+func TestWriteRegistryErrorBreakerOpen(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv := fakeRegistry(t)
+
+	t.Setenv("ANOTHER_API_URL", srv.URL)
+	t.Setenv("REGISTRY_MAX_ATTEMPTS", "1")
+	t.Setenv("REGISTRY_BREAKER_THRESHOLD", "1")
+	t.Setenv("REGISTRY_BREAKER_COOLDOWN", "1m")
+
+	client := registry.NewClient()
+
+	if _, err := client.GetPerson(context.Background(), "1234", "500"); err == nil {
+		t.Fatal("expected the first call to fail")
+	}
+
+	// the registry would answer now, but the breaker is open
+	_, err := client.GetPerson(context.Background(), "1234", "200")
+	w := assertRegistryResponse(t, err, http.StatusServiceUnavailable, true)
+
+	if got := w.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Retry-After = %q, want the breaker cooldown 60", got)
+	}
+}
+
+func TestWriteRegistryErrorOther(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	if writeRegistryError(c, "test", fmt.Errorf("database is down")) {
+		t.Error("non registry error was handled")
+	}
+}
+
+func assertRegistryResponse(t *testing.T, err error, status int, retryAfter bool) *httptest.ResponseRecorder {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	if !writeRegistryError(c, "test", err) {
+		t.Fatalf("error %v was not handled", err)
+	}
+
+	if w.Code != status {
+		t.Errorf("status = %d, want %d (err %v)", w.Code, status, err)
+	}
+
+	if got := w.Header().Get("Retry-After"); retryAfter {
+		if n, convErr := strconv.Atoi(got); convErr != nil || n < 1 {
+			t.Errorf("Retry-After = %q, want whole seconds >= 1", got)
+		}
+	} else if got != "" {
+		t.Errorf("unexpected Retry-After %q", got)
+	}
+
+	return w
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"effective_mobile_testing/internal/auth"
+	"effective_mobile_testing/internal/service"
+	"effective_mobile_testing/internal/service/repository"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary      Sync user with registry
+// @Description  re-fetch person data from the passport registry and return the field diff. With apply=true the diff is saved
+// @Tags         users
+// @Produce      json
+// @Param        id    path  int  true  "User ID"
+// @Param        apply query bool false "Save the registry data"
+// @Router       /user/{id}/sync [post]
+func (h *Handlers) SyncUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "syncUser"
+
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			slog.Error(fmt.Sprintf("%s error convertion: %v", handler, err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status bad request"})
+			return
+		}
+
+		apply := false
+		if s := c.Query("apply"); s != "" {
+			if apply, err = strconv.ParseBool(s); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "apply must be a boolean"})
+				return
+			}
+		}
+
+		res, err := h.service.SyncUser(c.Request.Context(), id, apply)
+		if err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if writeRegistryError(c, handler, err) {
+				return
+			}
+			switch {
+			case errors.Is(err, repository.ErrUserNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			case errors.Is(err, service.ErrNoPassport):
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			case errors.Is(err, repository.ErrVersionMismatch):
+				c.JSON(http.StatusConflict, gin.H{"error": "user was modified during sync, try again"})
+			case errors.Is(err, service.ErrRegistry):
+				slog.Error(fmt.Sprintf("%s error sync user: %v", handler, err))
+				c.JSON(http.StatusBadGateway, gin.H{"error": "registry is unavailable"})
+			default:
+				slog.Error(fmt.Sprintf("%s error sync user: %v", handler, err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			}
+			return
+		}
+
+		setETag(c, res.User)
+		maskSyncResult(c, res)
+		c.JSON(http.StatusOK, res)
+		slog.Debug(fmt.Sprintf("%s user synced, %d changes", handler, len(res.Changes)))
+	}
+}
+
+// @Summary      Registry sync report
+// @Description  persons whose registry data changed in the last scheduled re-sync
+// @Tags         users
+// @Produce      json
+// @Router       /users/sync/ [get]
+func (h *Handlers) GetSyncReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "getSyncReport"
+
+		report, err := h.service.GetSyncReport(c.Request.Context())
+		if err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, service.ErrNoSyncReport) {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+			slog.Error(fmt.Sprintf("%s error get sync report: %v", handler, err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		for i := range report.Changed {
+			maskSyncResult(c, &report.Changed[i])
+		}
+
+		c.JSON(http.StatusOK, report)
+	}
+}
@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"context"
+	"effective_mobile_testing/internal/auth"
+	"effective_mobile_testing/internal/model"
+	"effective_mobile_testing/internal/service"
+	"effective_mobile_testing/internal/service/repository"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TeamInterface interface {
+	CreateTeam(ctx context.Context, req model.CreateTeamRequest) (*model.Team, error)
+	GetTeams(ctx context.Context) ([]model.Team, error)
+	GetTeam(ctx context.Context, id int64) (*model.TeamDetail, error)
+	DeleteTeam(ctx context.Context, id int64) error
+	AddTeamMember(ctx context.Context, teamID int64, req model.AddTeamMemberRequest) error
+	EndTeamMember(ctx context.Context, teamID, personID int64, at string) error
+	GetTeamReport(ctx context.Context, id int64, from, to time.Time) (*model.TeamReport, error)
+}
+
+// @Summary      Create team
+// @Description  create a team or a department, admin only. A team may belong to a department via parent_id
+// @Tags         teams
+// @Accept       json
+// @Produce      json
+// @Param        input body model.CreateTeamRequest true "team"
+// @Router       /teams/ [post]
+func (h *Handlers) CreateTeam() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "createTeam"
+
+		var req model.CreateTeamRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			slog.Error(fmt.Sprintf("%s error with binding request:%v", handler, err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status bad request"})
+			return
+		}
+
+		team, err := h.teams.CreateTeam(c.Request.Context(), req)
+		if err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, service.ErrInvalidTeam) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, repository.ErrTeamExists) {
+				c.JSON(http.StatusConflict, gin.H{"error": "team already exists"})
+				return
+			}
+			if errors.Is(err, repository.ErrTeamNotFound) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "parent team not found"})
+				return
+			}
+			slog.Error(fmt.Sprintf("%s error create team: %v", handler, err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, team)
+		slog.Debug(fmt.Sprintf("%s team created", handler))
+	}
+}
+
+// @Summary      List teams
+// @Description  teams and departments, for managers and admins
+// @Tags         teams
+// @Produce      json
+// @Router       /teams/ [get]
+func (h *Handlers) GetTeams() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "getTeams"
+
+		teams, err := h.teams.GetTeams(c.Request.Context())
+		if err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			slog.Error(fmt.Sprintf("%s error get teams: %v", handler, err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"teams": teams})
+	}
+}
+
+// @Summary      Get team
+// @Description  team with all membership periods, for its managers and admins
+// @Tags         teams
+// @Produce      json
+// @Param        id path int true "Team ID"
+// @Router       /team/{id} [get]
+func (h *Handlers) GetTeam() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "getTeam"
+
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			slog.Error(fmt.Sprintf("%s error convertion: %v", handler, err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status bad request"})
+			return
+		}
+
+		team, err := h.teams.GetTeam(c.Request.Context(), id)
+		if err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, repository.ErrTeamNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "team not found"})
+				return
+			}
+			slog.Error(fmt.Sprintf("%s error get team: %v", handler, err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, team)
+	}
+}
+
+// @Summary      Delete team
+// @Description  delete the team with its memberships, admin only. Teams of a deleted department move to the top level
+// @Tags         teams
+// @Produce      json
+// @Param        id path int true "Team ID"
+// @Router       /team/{id} [delete]
+func (h *Handlers) DeleteTeam() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "deleteTeam"
+
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			slog.Error(fmt.Sprintf("%s error convertion: %v", handler, err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status bad request"})
+			return
+		}
+
+		if err := h.teams.DeleteTeam(c.Request.Context(), id); err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, repository.ErrTeamNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "team not found"})
+				return
+			}
+			slog.Error(fmt.Sprintf("%s error delete team: %v", handler, err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"status": "team deleted"})
+		slog.Debug(fmt.Sprintf("%s team deleted", handler))
+	}
+}
+
+// @Summary      Add team member
+// @Description  add a member or a manager for a period, admin only. Periods of one person in a team can't overlap
+// @Tags         teams
+// @Accept       json
+// @Produce      json
+// @Param        id    path int true "Team ID"
+// @Param        input body model.AddTeamMemberRequest true "membership"
+// @Router       /team/{id}/members [post]
+func (h *Handlers) AddTeamMember() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "addTeamMember"
+
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			slog.Error(fmt.Sprintf("%s error convertion: %v", handler, err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status bad request"})
+			return
+		}
+
+		var req model.AddTeamMemberRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			slog.Error(fmt.Sprintf("%s error with binding request:%v", handler, err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status bad request"})
+			return
+		}
+
+		if err := h.teams.AddTeamMember(c.Request.Context(), id, req); err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, service.ErrInvalidTeam) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, repository.ErrTeamNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "team not found"})
+				return
+			}
+			if errors.Is(err, repository.ErrUserNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+				return
+			}
+			if errors.Is(err, repository.ErrMembershipOverlaps) {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+			slog.Error(fmt.Sprintf("%s error add team member: %v", handler, err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"status": "member added"})
+		slog.Debug(fmt.Sprintf("%s member added", handler))
+	}
+}
+
+// @Summary      Remove team member
+// @Description  end the membership of the person at the date (today by default), admin only. Earlier time stays in reports
+// @Tags         teams
+// @Produce      json
+// @Param        id        path  int    true  "Team ID"
+// @Param        person_id query int    true  "User ID"
+// @Param        date      query string false "End date, YYYY-MM-DD, exclusive"
+// @Router       /team/{id}/members [delete]
+func (h *Handlers) EndTeamMember() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "endTeamMember"
+
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			slog.Error(fmt.Sprintf("%s error convertion: %v", handler, err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status bad request"})
+			return
+		}
+
+		personID, err := strconv.ParseInt(c.Query("person_id"), 10, 64)
+		if err != nil {
+			slog.Error(fmt.Sprintf("%s error convertion: %v", handler, err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status bad request"})
+			return
+		}
+
+		if err := h.teams.EndTeamMember(c.Request.Context(), id, personID, c.Query("date")); err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, service.ErrInvalidTeam) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, repository.ErrMemberNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "team member not found"})
+				return
+			}
+			slog.Error(fmt.Sprintf("%s error end team member: %v", handler, err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"status": "membership ended"})
+		slog.Debug(fmt.Sprintf("%s membership ended", handler))
+	}
+}
+
+// @Summary      Team labor report
+// @Description  time tracked by the members while they were in the team (with the teams of a department), for its managers and admins
+// @Tags         teams
+// @Produce      json
+// @Param        id   path  int    true  "Team ID"
+// @Param        from query string false "Period start, YYYY-MM-DD or RFC 3339, start of the month by default"
+// @Param        to   query string false "Period end, exclusive, YYYY-MM-DD or RFC 3339, now by default"
+// @Router       /team/{id}/report [get]
+func (h *Handlers) GetTeamReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "getTeamReport"
+
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			slog.Error(fmt.Sprintf("%s error convertion: %v", handler, err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status bad request"})
+			return
+		}
+
+		now := time.Now()
+		from, err := parsePeriod(c.Query("from"), time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be YYYY-MM-DD or RFC 3339"})
+			return
+		}
+		to, err := parsePeriod(c.Query("to"), now)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be YYYY-MM-DD or RFC 3339"})
+			return
+		}
+
+		report, err := h.teams.GetTeamReport(c.Request.Context(), id, from, to)
+		if err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, service.ErrInvalidTeam) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, repository.ErrTeamNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "team not found"})
+				return
+			}
+			slog.Error(fmt.Sprintf("%s error get team report: %v", handler, err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+func parsePeriod(s string, def time.Time) (time.Time, error) {
+	if s == "" {
+		return def, nil
+	}
+
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339, s)
+}
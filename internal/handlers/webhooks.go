@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"context"
+	"effective_mobile_testing/internal/auth"
+	"effective_mobile_testing/internal/model"
+	"effective_mobile_testing/internal/service/repository"
+	"effective_mobile_testing/internal/webhook"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookInterface interface {
+	Subscribe(ctx context.Context, req model.CreateWebhookRequest) (*model.Webhook, error)
+	Webhooks(ctx context.Context) ([]model.Webhook, error)
+	Unsubscribe(ctx context.Context, id int64) error
+	Deliveries(ctx context.Context, filter model.DeliveryFilter) (*model.DeliveryPage, error)
+	Replay(ctx context.Context, deliveryID int64) (*model.WebhookDelivery, error)
+}
+
+// @Summary      Create webhook
+// @Description  subscribe url to events. Secret is generated when empty and returned only here
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param  		 input body model.CreateWebhookRequest true "webhook"
+// @Router		 /webhooks/ [post]
+func (h *Handlers) CreateWebhook() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "createWebhook"
+
+		var req model.CreateWebhookRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			slog.Error(fmt.Sprintf("%s error with binding request:%v", handler, err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status bad request"})
+			return
+		}
+
+		w, err := h.webhooks.Subscribe(c.Request.Context(), req)
+		if err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, webhook.ErrInvalidURL) || errors.Is(err, webhook.ErrInternalTarget) || errors.Is(err, webhook.ErrUnknownEvent) || errors.Is(err, webhook.ErrNoEvents) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			slog.Error(fmt.Sprintf("%s error create webhook: %v", handler, err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, w)
+		slog.Debug(fmt.Sprintf("%s webhook created", handler))
+	}
+}
+
+// @Summary      List webhooks
+// @Description  list webhook subscriptions
+// @Tags         webhooks
+// @Produce      json
+// @Router		 /webhooks/ [get]
+func (h *Handlers) GetWebhooks() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "getWebhooks"
+
+		webhooks, err := h.webhooks.Webhooks(c.Request.Context())
+		if err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			slog.Error(fmt.Sprintf("%s error get webhooks: %v", handler, err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"webhooks": webhooks})
+	}
+}
+
+// @Summary      Delete webhook
+// @Description  delete webhook subscription with its delivery log
+// @Tags         webhooks
+// @Produce      json
+// @Param  		 id query string true "Webhook ID"
+// @Router		 /webhooks/ [delete]
+func (h *Handlers) DeleteWebhook() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "deleteWebhook"
+
+		id, err := strconv.ParseInt(c.Query("id"), 10, 64)
+		if err != nil {
+			slog.Error(fmt.Sprintf("%s error convertion: %v", handler, err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status bad request"})
+			return
+		}
+
+		if err := h.webhooks.Unsubscribe(c.Request.Context(), id); err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, repository.ErrWebhookNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+				return
+			}
+			slog.Error(fmt.Sprintf("%s error delete webhook: %v", handler, err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"status": "webhook deleted"})
+		slog.Debug(fmt.Sprintf("%s webhook deleted", handler))
+	}
+}
+
+// @Summary      Webhook deliveries
+// @Description  delivery log, newest first
+// @Tags         webhooks
+// @Produce      json
+// @Param        webhook_id query int    false "Webhook ID"
+// @Param        status     query string false "pending, delivered or failed"
+// @Param        limit      query int    false "Page size, capped by PAGE_MAX_SIZE"
+// @Param        cursor     query string false "next_cursor from the previous page"
+// @Param        total      query bool   false "Count all matching deliveries"
+// @Router		 /webhooks/deliveries/ [get]
+func (h *Handlers) GetWebhookDeliveries() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "getWebhookDeliveries"
+
+		var filter model.DeliveryFilter
+
+		if s := c.Query("webhook_id"); s != "" {
+			webhookID, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				slog.Error(fmt.Sprintf("%s error convertion: %v", handler, err))
+				c.JSON(http.StatusBadRequest, gin.H{"error": "status bad request"})
+				return
+			}
+			filter.WebhookID = webhookID
+		}
+
+		page, err := parsePage(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		filter.Status = c.Query("status")
+		filter.Cursor = page.cursor
+		filter.Limit = page.limit
+		filter.WithTotal = page.withTotal
+
+		deliveries, err := h.webhooks.Deliveries(c.Request.Context(), filter)
+		if err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, repository.ErrInvalidCursor) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			slog.Error(fmt.Sprintf("%s error get deliveries: %v", handler, err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		for i := range deliveries.Deliveries {
+			deliveries.Deliveries[i].Payload = maskPayload(c, deliveries.Deliveries[i].Payload)
+		}
+
+		c.JSON(http.StatusOK, deliveries)
+	}
+}
+
+// @Summary      Replay delivery
+// @Description  send a stored delivery again as a new delivery
+// @Tags         webhooks
+// @Produce      json
+// @Param  		 id query string true "Delivery ID"
+// @Router		 /webhooks/deliveries/replay/ [post]
+func (h *Handlers) ReplayWebhookDelivery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const handler = "replayWebhookDelivery"
+
+		id, err := strconv.ParseInt(c.Query("id"), 10, 64)
+		if err != nil {
+			slog.Error(fmt.Sprintf("%s error convertion: %v", handler, err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status bad request"})
+			return
+		}
+
+		delivery, err := h.webhooks.Replay(c.Request.Context(), id)
+		if err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, repository.ErrDeliveryNotFound) || errors.Is(err, repository.ErrWebhookNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+			slog.Error(fmt.Sprintf("%s error replay delivery: %v", handler, err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		delivery.Payload = maskPayload(c, delivery.Payload)
+		c.JSON(http.StatusAccepted, delivery)
+		slog.Debug(fmt.Sprintf("%s delivery %d replayed", handler, id))
+	}
+}
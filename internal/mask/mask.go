@@ -0,0 +1,118 @@
+package mask
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	// "4512 654321". Ten digits without the space are left alone, they are more often timestamps
+	// and ids than passports. Known personal fields are masked by name, see Query and the slog Handler
+	passportRe = regexp.MustCompile(`\b(\d{2})\d{2} \d{3}(\d{3})\b`)
+	// user:password@ in connection strings and URLs
+	credentialsRe = regexp.MustCompile(`(://[^:/@\s]+):[^@\s]+@`)
+	// password=... in key/value DSNs
+	passwordRe = regexp.MustCompile(`(?i)(password=)\S+`)
+)
+
+// Passport keeps the first two digits of the series and the last three of the number: "45** ***321".
+func Passport(p string) string {
+	fields := strings.Fields(p)
+	if len(fields) != 2 || len(fields[0]) < 2 || len(fields[1]) < 3 {
+		return strings.Repeat("*", len(p))
+	}
+
+	series, number := fields[0], fields[1]
+
+	return series[:2] + strings.Repeat("*", len(series)-2) + " " +
+		strings.Repeat("*", len(number)-3) + number[len(number)-3:]
+}
+
+// Address keeps only the first part, usually the city: "г. Москва, ***".
+func Address(a string) string {
+	if a == "" {
+		return ""
+	}
+
+	head, _, found := strings.Cut(a, ",")
+	if !found {
+		return "***"
+	}
+
+	return head + ", ***"
+}
+
+// Name keeps the first letter of every word: "Иванов Иван" gives "И*** И***".
+func Name(n string) string {
+	words := strings.Fields(n)
+	for i, w := range words {
+		r, _ := utf8.DecodeRuneInString(w)
+		words[i] = string(r) + "***"
+	}
+
+	return strings.Join(words, " ")
+}
+
+// DSN hides the password of a database connection string.
+func DSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.User != nil {
+		return u.Redacted()
+	}
+
+	return passwordRe.ReplaceAllString(dsn, "${1}***")
+}
+
+// queryMasks mask personal query parameters by name.
+var queryMasks = map[string]func(string) string{
+	"passport_number": Passport,
+	"surname":         Name,
+	"name":            Name,
+	"patronymic":      Name,
+	"q":               Name,
+	"address":         Address,
+	// carries values of the last row of a page
+	"cursor": func(string) string { return "***" },
+}
+
+// Query masks the values of personal and secret parameters of a raw URL query, the others are kept.
+// Parameters are sorted by name.
+func Query(raw string) string {
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return redacted
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+
+	for _, k := range keys {
+		for _, v := range values[k] {
+			if m, ok := queryMasks[strings.ToLower(k)]; ok {
+				v = m(v)
+			} else if isSecretKey(k) {
+				v = redacted
+			}
+			parts = append(parts, k+"="+v)
+		}
+	}
+
+	return strings.Join(parts, "&")
+}
+
+// Text masks passport numbers and credentials found anywhere in s.
+func Text(s string) string {
+	s = passportRe.ReplaceAllString(s, "$1** ***$2")
+	s = credentialsRe.ReplaceAllString(s, "$1:***@")
+
+	return passwordRe.ReplaceAllString(s, "${1}***")
+}
@@ -0,0 +1,40 @@
+package mask
+
+import "testing"
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"passport_number=1234+567890&limit=10", "limit=10&passport_number=12** ***890"},
+		{"surname=%D0%98%D0%B2%D0%B0%D0%BD%D0%BE%D0%B2&surname_match=prefix", "surname=И***&surname_match=prefix"},
+		{"q=ivan+petrov&address=%D0%B3.+%D0%9C%D0%BE%D1%81%D0%BA%D0%B2%D0%B0%2C+%D1%83%D0%BB.+1", "address=г. Москва, ***&q=i*** p***"},
+		{"cursor=eyJzIjoic3VybmFtZSJ9&id=1700000000", "cursor=***&id=1700000000"},
+		{"api_key=tt_secret", "api_key=" + redacted},
+	}
+
+	for _, tt := range tests {
+		if got := Query(tt.raw); got != tt.want {
+			t.Errorf("Query(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"passport 4512 654321 exists", "passport 45** ***321 exists"},
+		// unix timestamps and ids aren't passports
+		{"created 1700000000, id 4512654321", "created 1700000000, id 4512654321"},
+		{"postgres://user:pass@db/x", "postgres://user:***@db/x"},
+	}
+
+	for _, tt := range tests {
+		if got := Text(tt.in); got != tt.want {
+			t.Errorf("Text(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
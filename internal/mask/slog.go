@@ -0,0 +1,88 @@
+package mask
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// keys whose values are never logged
+var secretKeys = []string{"password", "secret", "token", "authorization", "api_key", "apikey", "dsn", "cookie"}
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, secret := range secretKeys {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Handler masks personal data and secrets in every record before passing it on:
+// attributes are masked by key, the message and other strings by content.
+type Handler struct {
+	next slog.Handler
+}
+
+func NewHandler(next slog.Handler) *Handler {
+	return &Handler{next: next}
+}
+
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	masked := slog.NewRecord(r.Time, r.Level, Text(r.Message), r.PC)
+
+	r.Attrs(func(a slog.Attr) bool {
+		masked.AddAttrs(attr(a))
+		return true
+	})
+
+	return h.next.Handle(ctx, masked)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	masked := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		masked[i] = attr(a)
+	}
+
+	return &Handler{next: h.next.WithAttrs(masked)}
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{next: h.next.WithGroup(name)}
+}
+
+func attr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	key := strings.ToLower(a.Key)
+
+	if a.Value.Kind() == slog.KindGroup {
+		group := a.Value.Group()
+		masked := make([]any, len(group))
+		for i, g := range group {
+			masked[i] = attr(g)
+		}
+		return slog.Group(a.Key, masked...)
+	}
+
+	if isSecretKey(key) {
+		return slog.String(a.Key, redacted)
+	}
+
+	switch {
+	case strings.Contains(key, "passport"):
+		return slog.String(a.Key, Passport(a.Value.String()))
+	case key == "address":
+		return slog.String(a.Key, Address(a.Value.String()))
+	case a.Value.Kind() == slog.KindString || a.Value.Kind() == slog.KindAny:
+		return slog.String(a.Key, Text(a.Value.String()))
+	}
+
+	return a
+}
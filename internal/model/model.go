@@ -1,16 +1,19 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 )
 
 type User struct {
-	ID             int64  `json:"id,omitempty"`
-	Surname        string `json:"surname"`
-	Name           string `json:"name"`
-	Patronymic     string `json:"patronymic,omitempty"`
-	Address        string `json:"address"`
-	PassportNumber string `json:"passport_number,omitempty"`
+	ID             int64      `json:"id,omitempty"`
+	Surname        string     `json:"surname"`
+	Name           string     `json:"name"`
+	Patronymic     string     `json:"patronymic,omitempty"`
+	Address        string     `json:"address"`
+	PassportNumber string     `json:"passport_number,omitempty"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+	Version        int64      `json:"version,omitempty"`
 }
 
 type Task struct {
@@ -61,10 +64,495 @@ type Args struct {
 	PassportNumber string `json:"passport_number	,omitempty"`
 }
 
-type UserUpdateRequest struct {
-	Surname        string `json:"surname,omitempty"`
-	Name           string `json:"name,omitempty"`
-	Patronymic     string `json:"patronymic,omitempty"`
-	Address        string `json:"address,omitempty"`
-	PassportNumber string `json:"passport_number,omitempty"`
+const (
+	BulkJobRunning = "running"
+	BulkJobDone    = "done"
+
+	BulkRowPending         = "pending"
+	BulkRowCreated         = "created"
+	BulkRowAlreadyExists   = "already_exists"
+	BulkRowInvalidPassport = "invalid_passport"
+	BulkRowAPIFailure      = "api_failure"
+	BulkRowFailed          = "failed"
+)
+
+type BulkCreateRequest struct {
+	PassportNumbers []string `json:"passport_numbers"`
+}
+
+type BulkJob struct {
+	ID         string       `json:"id"`
+	Status     string       `json:"status"`
+	Total      int          `json:"total"`
+	Processed  int          `json:"processed"`
+	CreatedAt  time.Time    `json:"created_at"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+	Rows       []BulkJobRow `json:"rows"`
+}
+
+type BulkJobRow struct {
+	PassportNumber string `json:"passport_number"`
+	Status         string `json:"status"`
+	UserID         int64  `json:"user_id,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+const (
+	EventUserCreated     = "user.created"
+	EventUserUpdated     = "user.updated"
+	EventUserDeleted     = "user.deleted"
+	EventUserRestored    = "user.restored"
+	EventUserMerged      = "user.merged"
+	EventUserErased      = "user.erased"
+	EventTrackingStarted = "tracking.started"
+	EventTrackingStopped = "tracking.stopped"
+
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+type Event struct {
+	Event      string    `json:"event"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       any       `json:"data"`
+}
+
+type Webhook struct {
+	ID        int64     `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret,omitempty"`
+	Events []string `json:"events"`
+}
+
+type WebhookDelivery struct {
+	ID           int64           `json:"id"`
+	WebhookID    int64           `json:"webhook_id"`
+	Event        string          `json:"event"`
+	Payload      json.RawMessage `json:"payload"`
+	Status       string          `json:"status"`
+	Attempts     int             `json:"attempts"`
+	ResponseCode *int            `json:"response_code,omitempty"`
+	LastError    *string         `json:"last_error,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+	DeliveredAt  *time.Time      `json:"delivered_at,omitempty"`
+	// set while the delivery is pending
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+}
+
+// UserEvent is the payload of user.created, user.updated and user.restored. Events leave the
+// system, so they carry no personal data: consumers read the person through the API.
+type UserEvent struct {
+	ID      int64    `json:"id"`
+	Version int64    `json:"version"`
+	Fields  []string `json:"fields,omitempty"` // changed fields of user.updated
+}
+
+type UserDeletedEvent struct {
+	ID int64 `json:"id"`
+}
+
+type UserMergedEvent struct {
+	SurvivorID  int64 `json:"survivor_id"`
+	DuplicateID int64 `json:"duplicate_id"`
+	MovedTasks  int64 `json:"moved_tasks"`
+}
+
+const (
+	PurgeDelete    = "delete"
+	PurgeAnonymize = "anonymize"
+)
+
+type TrackingEvent struct {
+	UserID   int64     `json:"user_id"`
+	TaskName string    `json:"task_name"`
+	At       time.Time `json:"at"`
+}
+
+type OutboxMessage struct {
+	ID      int64
+	Event   string
+	Payload []byte
+}
+
+const (
+	MatchExact    = "exact"
+	MatchPrefix   = "prefix"
+	MatchContains = "contains"
+	MatchFuzzy    = "fuzzy"
+)
+
+type FieldFilter struct {
+	Value string
+	Mode  string
+}
+
+type UserFilter struct {
+	ID             int64
+	Surname        FieldFilter
+	Name           FieldFilter
+	Patronymic     FieldFilter
+	Address        FieldFilter
+	PassportNumber string
+	// Query is searched in surname, name, patronymic and address, every word must match some field.
+	Query string
+	// Sort is a comma separated list of columns, "-" before a column sorts it descending.
+	Sort string
+	// Cursor is next_cursor of the previous page, it can't be combined with Offset.
+	Cursor         string
+	Limit          int
+	Offset         int
+	WithTotal      bool
+	IncludeDeleted bool
+	// ManagerID limits the list to the manager and the members of the teams they manage, 0 lists everyone.
+	ManagerID int64
+}
+
+type Page struct {
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      *int64 `json:"total,omitempty"`
+}
+
+type UserPage struct {
+	Users []User `json:"users"`
+	Page
+}
+
+type DeliveryFilter struct {
+	WebhookID int64
+	Status    string
+	Cursor    string
+	Limit     int
+	WithTotal bool
+}
+
+type DeliveryPage struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	Page
+}
+
+const (
+	HistoryCreated  = "created"
+	HistoryUpdated  = "updated"
+	HistoryDeleted  = "deleted"
+	HistoryRestored = "restored"
+	HistoryMerged   = "merged"
+	HistoryExported = "exported"
+	HistoryErased   = "erased"
+)
+
+type FieldChange struct {
+	Old *string `json:"old"`
+	New *string `json:"new"`
+}
+
+// NewFieldChange records a change of a text field, an empty value is kept as null.
+func NewFieldChange(old, new string) FieldChange {
+	return FieldChange{Old: optional(old), New: optional(new)}
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return &s
+}
+
+type PersonChange struct {
+	ID        int64                  `json:"id"`
+	PersonID  int64                  `json:"person_id"`
+	Action    string                 `json:"action"`
+	Actor     string                 `json:"actor"`
+	Changes   map[string]FieldChange `json:"changes"`
+	ChangedAt time.Time              `json:"changed_at"`
+}
+
+type LaborCostFilter struct {
+	UserID    int64
+	Cursor    string
+	Limit     int
+	WithTotal bool
+}
+
+type LaborCostPage struct {
+	Costs []ResponseLobarCost `json:"costs"`
+	Page
+}
+
+type HistoryFilter struct {
+	PersonID  int64
+	Cursor    string
+	Limit     int
+	WithTotal bool
+}
+
+type HistoryPage struct {
+	History []PersonChange `json:"history"`
+	Page
+}
+
+// PatchField is one field of a JSON Merge Patch (RFC 7396): Set is false when the
+// field was omitted, Value is nil when it was an explicit null.
+type PatchField struct {
+	Set   bool
+	Value *string
+}
+
+func (f *PatchField) UnmarshalJSON(b []byte) error {
+	f.Set = true
+
+	if string(b) == "null" {
+		f.Value = nil
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	f.Value = &s
+
+	return nil
+}
+
+// UserPatch is the body of PATCH /user/, every field is documented as the string it is sent as.
+type UserPatch struct {
+	Surname        PatchField `json:"surname" swaggertype:"string"`
+	Name           PatchField `json:"name" swaggertype:"string"`
+	Patronymic     PatchField `json:"patronymic" swaggertype:"string"`
+	Address        PatchField `json:"address" swaggertype:"string"`
+	PassportNumber PatchField `json:"passport_number" swaggertype:"string"`
+}
+
+// SyncResult is the difference between a stored person and the passport registry.
+type SyncResult struct {
+	UserID  int64                  `json:"user_id"`
+	Changes map[string]FieldChange `json:"changes"`
+	Applied bool                   `json:"applied"`
+	User    *User                  `json:"user,omitempty"`
+}
+
+type SyncFailure struct {
+	UserID int64  `json:"user_id"`
+	Error  string `json:"error"`
+}
+
+// SyncReport is the outcome of re-syncing every person with the registry.
+type SyncReport struct {
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt time.Time     `json:"finished_at"`
+	Apply      bool          `json:"apply"`
+	Checked    int           `json:"checked"`
+	Changed    []SyncResult  `json:"changed"`
+	Failed     []SyncFailure `json:"failed"`
+}
+
+type RunningTask struct {
+	TaskName        string    `json:"task_name"`
+	StartTracking   time.Time `json:"start_tracking"`
+	DurationMinutes int       `json:"duration_minutes"`
+}
+
+type TaskSummary struct {
+	TaskName        string     `json:"task_name"`
+	StartTracking   time.Time  `json:"start_tracking"`
+	StopTracking    *time.Time `json:"stop_tracking,omitempty"`
+	DurationMinutes int        `json:"duration_minutes"`
+}
+
+// TrackingSummary counts the running session too, up to now.
+type TrackingSummary struct {
+	Running      *RunningTask  `json:"running,omitempty"`
+	TodayMinutes int           `json:"today_minutes"`
+	WeekMinutes  int           `json:"week_minutes"`
+	MonthMinutes int           `json:"month_minutes"`
+	RecentTasks  []TaskSummary `json:"recent_tasks"`
+}
+
+type UserDetail struct {
+	User     User            `json:"user"`
+	Tracking TrackingSummary `json:"tracking"`
+}
+
+// DuplicateCandidate is a person that may be the same human as the one asked about.
+type DuplicateCandidate struct {
+	User              User     `json:"user"`
+	Score             float64  `json:"score"`
+	NameSimilarity    float64  `json:"name_similarity"`
+	AddressSimilarity float64  `json:"address_similarity"`
+	SamePassport      bool     `json:"same_passport"`
+	Reasons           []string `json:"reasons"`
+}
+
+type MergeUsersRequest struct {
+	SurvivorID  int64 `json:"survivor_id"`
+	DuplicateID int64 `json:"duplicate_id"`
+}
+
+type ExportedTask struct {
+	ID            int64      `json:"id"`
+	Name          string     `json:"name"`
+	StartTracking *time.Time `json:"start_tracking"`
+	StopTracking  *time.Time `json:"stop_tracking"`
+}
+
+// PersonalDataExport is everything stored about one person, for data subject requests.
+type PersonalDataExport struct {
+	ExportedAt time.Time      `json:"exported_at"`
+	User       User           `json:"user"`
+	Tasks      []ExportedTask `json:"tasks"`
+	History    []PersonChange `json:"history"`
+}
+
+// APIKey is a stored API key. Only the hash of the key is kept, Prefix helps to tell keys apart.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Roles      []string   `json:"roles"`
+	PersonID   *int64     `json:"person_id,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// CreateAPIKeyRequest describes a new key. Manager and employee keys belong to a person.
+type CreateAPIKeyRequest struct {
+	Name     string   `json:"name"`
+	Roles    []string `json:"roles"`
+	PersonID int64    `json:"person_id,omitempty"`
+}
+
+// CreatedAPIKey carries the key itself, it is shown only once.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+const (
+	TeamKindTeam       = "team"
+	TeamKindDepartment = "department"
+
+	TeamRoleMember  = "member"
+	TeamRoleManager = "manager"
+)
+
+// Team is a team or a department. A department includes the teams below it in reports and management.
+type Team struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	ParentID  *int64    `json:"parent_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateTeamRequest struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	ParentID *int64 `json:"parent_id,omitempty"`
+}
+
+// TeamMember is one membership period, ValidTo is exclusive and empty for an open membership.
+type TeamMember struct {
+	ID         int64      `json:"id"`
+	TeamID     int64      `json:"team_id"`
+	PersonID   int64      `json:"person_id"`
+	Surname    string     `json:"surname"`
+	Name       string     `json:"name"`
+	Patronymic string     `json:"patronymic,omitempty"`
+	Role       string     `json:"role"`
+	ValidFrom  time.Time  `json:"valid_from"`
+	ValidTo    *time.Time `json:"valid_to,omitempty"`
+}
+
+// AddTeamMemberRequest dates are YYYY-MM-DD, ValidFrom is today when empty.
+type AddTeamMemberRequest struct {
+	PersonID  int64  `json:"person_id"`
+	Role      string `json:"role"`
+	ValidFrom string `json:"valid_from"`
+	ValidTo   string `json:"valid_to"`
+}
+
+type TeamDetail struct {
+	Team    Team         `json:"team"`
+	Members []TeamMember `json:"members"`
+}
+
+// MemberLabor is the time a person tracked while being a member of the team.
+type MemberLabor struct {
+	PersonID   int64  `json:"person_id"`
+	Surname    string `json:"surname"`
+	Name       string `json:"name"`
+	Patronymic string `json:"patronymic,omitempty"`
+	Tasks      int    `json:"tasks"`
+	Minutes    int64  `json:"minutes"`
+}
+
+// TeamReport aggregates the time of the team members (and of the teams below a department) over [From, To).
+type TeamReport struct {
+	Team         Team          `json:"team"`
+	From         time.Time     `json:"from"`
+	To           time.Time     `json:"to"`
+	TotalMinutes int64         `json:"total_minutes"`
+	Members      []MemberLabor `json:"members"`
+}
+
+// Reads of personal data recorded in the access audit.
+const (
+	AccessUsersList  = "users.list"
+	AccessUserDetail = "user.detail"
+	AccessUserExport = "user.export"
+)
+
+// AccessAuditEntry records who read which persons and with what filter.
+type AccessAuditEntry struct {
+	ID        int64             `json:"id"`
+	Actor     string            `json:"actor"`
+	Action    string            `json:"action"`
+	Filter    map[string]string `json:"filter"`
+	PersonIDs []int64           `json:"person_ids"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+type AccessAuditFilter struct {
+	Actor     string
+	Action    string
+	PersonID  int64
+	From      *time.Time
+	To        *time.Time
+	Cursor    string
+	Limit     int
+	WithTotal bool
+}
+
+type AccessAuditPage struct {
+	Entries []AccessAuditEntry `json:"entries"`
+	Page
+}
+
+// RegistryCacheEntry is a cached registry answer. A nil User means the passport wasn't found.
+type RegistryCacheEntry struct {
+	User      *UserFromAPI
+	ExpiresAt time.Time
+}
+
+// RegistryCacheStats are counted by this instance since it started.
+type RegistryCacheStats struct {
+	Backend      string  `json:"backend"`
+	Hits         int64   `json:"hits"`
+	NegativeHits int64   `json:"negative_hits"`
+	Misses       int64   `json:"misses"`
+	StoreErrors  int64   `json:"store_errors"`
+	HitRatio     float64 `json:"hit_ratio"`
+	// only known for the in-memory cache
+	Entries *int `json:"entries,omitempty"`
 }
@@ -0,0 +1,44 @@
+package outbox
+
+import (
+	"effective_mobile_testing/internal/model"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const flushTimeout = 5 * time.Second
+
+// NATSSink publishes outbox messages to <prefix>.<event>, e.g. time_tracker.user.created.
+// Nats-Msg-Id carries the outbox id so JetStream streams and consumers can drop duplicates.
+type NATSSink struct {
+	conn   *nats.Conn
+	prefix string
+}
+
+func NewNATSSink(url, prefix string) (*NATSSink, error) {
+	conn, err := nats.Connect(url, nats.Name("time-tracker outbox relay"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+
+	return &NATSSink{conn: conn, prefix: prefix}, nil
+}
+
+func (s *NATSSink) Send(msg model.OutboxMessage) error {
+	m := nats.NewMsg(s.prefix + "." + msg.Event)
+	m.Header.Set(nats.MsgIdHdr, strconv.FormatInt(msg.ID, 10))
+	m.Data = msg.Payload
+
+	if err := s.conn.PublishMsg(m); err != nil {
+		return err
+	}
+
+	// flush so the message is known to have reached the server before the row is marked published
+	return s.conn.FlushTimeout(flushTimeout)
+}
+
+func (s *NATSSink) Close() {
+	s.conn.Close()
+}
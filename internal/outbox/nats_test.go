@@ -0,0 +1,74 @@
+package outbox
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"effective_mobile_testing/internal/model"
+
+	"github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+)
+
+func TestNATSSinkSend(t *testing.T) {
+	srv := test.RunRandClientPortServer()
+	t.Cleanup(srv.Shutdown)
+
+	sink, err := NewNATSSink(srv.ClientURL(), "time_tracker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(sink.Close)
+
+	conn, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(conn.Close)
+
+	sub, err := conn.SubscribeSync("time_tracker.>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := model.OutboxMessage{ID: 42, Event: model.EventUserCreated, Payload: []byte(`{"event":"user.created","data":{"id":1}}`)}
+	if err := sink.Send(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := sub.NextMsg(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "time_tracker." + model.EventUserCreated; got.Subject != want {
+		t.Errorf("subject = %q, want %q", got.Subject, want)
+	}
+	if id := got.Header.Get(nats.MsgIdHdr); id != strconv.FormatInt(msg.ID, 10) {
+		t.Errorf("%s = %q, want the outbox id %d", nats.MsgIdHdr, id, msg.ID)
+	}
+	if string(got.Data) != string(msg.Payload) {
+		t.Errorf("data = %s, want %s", got.Data, msg.Payload)
+	}
+}
+
+func TestNATSSinkSendServerDown(t *testing.T) {
+	srv := test.RunRandClientPortServer()
+
+	sink, err := NewNATSSink(srv.ClientURL(), "time_tracker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(sink.Close)
+
+	srv.Shutdown()
+
+	// the relay must not mark the message published when the server didn't get it
+	if err := sink.Send(model.OutboxMessage{ID: 1, Event: model.EventUserCreated, Payload: []byte(`{}`)}); err == nil {
+		t.Error("expected an error with the server down")
+	}
+}
@@ -0,0 +1,85 @@
+package outbox
+
+import (
+	"context"
+	"effective_mobile_testing/internal/config"
+	"effective_mobile_testing/internal/model"
+	"log/slog"
+	"time"
+)
+
+type Repository interface {
+	PublishOutbox(limit int, publish func(model.OutboxMessage) error) (int, error)
+	PurgeOutbox(before time.Time) (int64, error)
+}
+
+// Sink receives every committed outbox message. Delivery is at-least-once: a message is
+// sent to all sinks again if any of them fails, so sinks drop messages by ID they already got.
+type Sink interface {
+	Send(msg model.OutboxMessage) error
+}
+
+type Relay struct {
+	repo      Repository
+	sinks     []Sink
+	interval  time.Duration
+	batch     int
+	retention time.Duration
+}
+
+func NewRelay(repo Repository, sinks ...Sink) *Relay {
+	return &Relay{
+		repo:      repo,
+		sinks:     sinks,
+		interval:  config.GetOutboxPollInterval(),
+		batch:     config.GetOutboxBatchSize(),
+		retention: config.GetOutboxRetention(),
+	}
+}
+
+// Run polls the outbox until ctx is done. A full batch is followed immediately by the next one.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := r.repo.PublishOutbox(r.batch, r.publish)
+		if err != nil {
+			slog.Error("can't publish outbox", slog.String("err", err.Error()))
+		}
+
+		if err == nil && n == r.batch {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge deletes messages published longer than OUTBOX_RETENTION ago.
+func (r *Relay) Purge(ctx context.Context) error {
+	n, err := r.repo.PurgeOutbox(time.Now().Add(-r.retention))
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		slog.Info("published outbox messages purged", slog.Int64("count", n))
+	}
+
+	return nil
+}
+
+func (r *Relay) publish(msg model.OutboxMessage) error {
+	for _, s := range r.sinks {
+		if err := s.Send(msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
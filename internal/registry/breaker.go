@@ -0,0 +1,76 @@
+package registry
+
+import (
+	"sync"
+	"time"
+)
+
+// breaker opens after threshold failed calls in a row and rejects calls for cooldown.
+// After the cooldown one call is let through: success closes the breaker, failure opens it again.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{threshold: threshold, cooldown: cooldown}
+}
+
+// allow tells whether a call may be made now, and if not, how long the breaker stays open.
+func (b *breaker) allow() (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true, 0
+	}
+
+	if wait := time.Until(b.openUntil); wait > 0 {
+		return false, wait
+	}
+	// the probe decides whether the breaker opens for another cooldown, so callers wait at least as long
+	if b.probing {
+		return false, max(b.cooldown, time.Second)
+	}
+
+	b.probing = true
+
+	return true, 0
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.probing = false
+}
+
+// release ends a call cancelled by the caller without counting it.
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// failure counts a failed call and returns how long the breaker is open now, 0 when it is closed.
+func (b *breaker) failure() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+
+	if b.failures < b.threshold {
+		return 0
+	}
+
+	b.openUntil = time.Now().Add(b.cooldown)
+
+	return b.cooldown
+}
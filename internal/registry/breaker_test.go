@@ -0,0 +1,31 @@
+package registry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreakerWaitsWhileProbing(t *testing.T) {
+	b := newBreaker(1, time.Millisecond)
+
+	b.failure()
+	time.Sleep(2 * time.Millisecond)
+
+	if ok, _ := b.allow(); !ok {
+		t.Fatal("probe not let through after the cooldown")
+	}
+
+	ok, wait := b.allow()
+	if ok {
+		t.Fatal("second call let through while probing")
+	}
+	if wait < time.Second {
+		t.Fatalf("wait %v while probing, want at least a second", wait)
+	}
+
+	b.success()
+
+	if ok, _ := b.allow(); !ok {
+		t.Fatal("breaker not closed after a successful probe")
+	}
+}
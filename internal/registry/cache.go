@@ -0,0 +1,149 @@
+package registry
+
+import (
+	"context"
+	"effective_mobile_testing/internal/config"
+	"effective_mobile_testing/internal/model"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Store keeps registry answers by passport ("<series> <number>").
+// GetRegistryCache returns nil when the passport isn't cached or its entry expired.
+type Store interface {
+	GetRegistryCache(passport string) (*model.RegistryCacheEntry, error)
+	SetRegistryCache(passport string, entry model.RegistryCacheEntry) error
+	PurgeRegistryCache() (int64, error)
+	DeleteRegistryCache(passport string) error
+}
+
+// Cache answers repeated lookups of the same passport without calling the registry.
+// Found persons are kept for REGISTRY_CACHE_TTL, unknown passports for REGISTRY_CACHE_NEGATIVE_TTL.
+// Other failures are never cached. A failing store is logged and the registry is called.
+type Cache struct {
+	client      *Client
+	store       Store
+	backend     string
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	hits         atomic.Int64
+	negativeHits atomic.Int64
+	misses       atomic.Int64
+	storeErrors  atomic.Int64
+}
+
+// NewCache puts the cache chosen by REGISTRY_CACHE in front of client.
+// shared is the Postgres store used with REGISTRY_CACHE=postgres.
+func NewCache(client *Client, shared Store) *Cache {
+	c := &Cache{
+		client:      client,
+		backend:     config.GetRegistryCache(),
+		ttl:         config.GetRegistryCacheTTL(),
+		negativeTTL: config.GetRegistryCacheNegativeTTL(),
+	}
+
+	switch c.backend {
+	case "postgres":
+		c.store = shared
+	case "memory":
+		c.store = newLRU(config.GetRegistryCacheSize())
+	}
+
+	return c
+}
+
+func (c *Cache) GetPerson(ctx context.Context, passportSerie, passportNumber string) (model.UserFromAPI, error) {
+	if c.store == nil {
+		return c.client.GetPerson(ctx, passportSerie, passportNumber)
+	}
+
+	passport := passportSerie + " " + passportNumber
+
+	entry, err := c.store.GetRegistryCache(passport)
+	if err != nil {
+		c.storeErrors.Add(1)
+		slog.Error("can't read registry cache", slog.String("err", err.Error()))
+	}
+
+	if entry != nil {
+		if entry.User == nil {
+			c.negativeHits.Add(1)
+			return model.UserFromAPI{}, ErrPassportNotFound
+		}
+
+		c.hits.Add(1)
+		return *entry.User, nil
+	}
+
+	c.misses.Add(1)
+
+	user, err := c.client.GetPerson(ctx, passportSerie, passportNumber)
+	switch {
+	case err == nil:
+		c.set(passport, model.RegistryCacheEntry{User: &user, ExpiresAt: time.Now().Add(c.ttl)})
+	case errors.Is(err, ErrPassportNotFound):
+		c.set(passport, model.RegistryCacheEntry{ExpiresAt: time.Now().Add(c.negativeTTL)})
+	}
+
+	return user, err
+}
+
+func (c *Cache) set(passport string, entry model.RegistryCacheEntry) {
+	if err := c.store.SetRegistryCache(passport, entry); err != nil {
+		c.storeErrors.Add(1)
+		slog.Error("can't write registry cache", slog.String("err", err.Error()))
+	}
+}
+
+// Forget drops the entry of the passport, so erased personal data isn't served from the cache.
+// Other instances with an in-memory cache keep their entry until it expires.
+func (c *Cache) Forget(passport string) error {
+	if c.store == nil {
+		return nil
+	}
+
+	return c.store.DeleteRegistryCache(strings.Join(strings.Fields(passport), " "))
+}
+
+// Purge removes expired entries. It is run by the scheduler.
+func (c *Cache) Purge(_ context.Context) error {
+	if c.store == nil {
+		return nil
+	}
+
+	n, err := c.store.PurgeRegistryCache()
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		slog.Debug("expired registry cache entries removed", slog.Int64("count", n))
+	}
+
+	return nil
+}
+
+func (c *Cache) Stats() model.RegistryCacheStats {
+	stats := model.RegistryCacheStats{
+		Backend:      c.backend,
+		Hits:         c.hits.Load(),
+		NegativeHits: c.negativeHits.Load(),
+		Misses:       c.misses.Load(),
+		StoreErrors:  c.storeErrors.Load(),
+	}
+
+	if total := stats.Hits + stats.NegativeHits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits+stats.NegativeHits) / float64(total)
+	}
+
+	if l, ok := c.store.(*lru); ok {
+		n := l.len()
+		stats.Entries = &n
+	}
+
+	return stats
+}
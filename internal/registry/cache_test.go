@@ -0,0 +1,131 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"effective_mobile_testing/internal/model"
+)
+
+func newTestCache(t *testing.T, size int) (*Cache, *atomic.Int32) {
+	t.Helper()
+
+	var calls atomic.Int32
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Query().Get("passportNumber") == "000000" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"surname":"Иванов","name":"Иван"}`)
+	})
+
+	return &Cache{
+		client:      client,
+		store:       newLRU(size),
+		backend:     "memory",
+		ttl:         time.Hour,
+		negativeTTL: 50 * time.Millisecond,
+	}, &calls
+}
+
+func TestCacheHits(t *testing.T) {
+	c, calls := newTestCache(t, 10)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetPerson(ctx, "1234", "567890"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("registry calls = %d, want 1", got)
+	}
+
+	stats := c.Stats()
+	if stats.Hits != 2 || stats.Misses != 1 || stats.Entries == nil || *stats.Entries != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestCacheNegativeTTL(t *testing.T) {
+	c, calls := newTestCache(t, 10)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetPerson(ctx, "1234", "000000"); !errors.Is(err, ErrPassportNotFound) {
+			t.Fatalf("err = %v, want ErrPassportNotFound", err)
+		}
+	}
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("registry calls = %d, want 1", got)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	if _, err := c.GetPerson(ctx, "1234", "000000"); !errors.Is(err, ErrPassportNotFound) {
+		t.Fatalf("err = %v, want ErrPassportNotFound", err)
+	}
+
+	if got := calls.Load(); got != 2 {
+		t.Errorf("registry calls after the negative ttl = %d, want 2", got)
+	}
+
+	if stats := c.Stats(); stats.NegativeHits != 1 {
+		t.Errorf("negative hits = %d, want 1", stats.NegativeHits)
+	}
+}
+
+func TestCacheForget(t *testing.T) {
+	c, calls := newTestCache(t, 10)
+	ctx := context.Background()
+
+	if _, err := c.GetPerson(ctx, "1234", "567890"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Forget(" 1234  567890 "); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.GetPerson(ctx, "1234", "567890"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := calls.Load(); got != 2 {
+		t.Errorf("registry calls = %d, want 2 after Forget", got)
+	}
+}
+
+func TestLRUEviction(t *testing.T) {
+	l := newLRU(2)
+	entry := model.RegistryCacheEntry{User: &model.UserFromAPI{Surname: "Иванов"}, ExpiresAt: time.Now().Add(time.Hour)}
+
+	for _, p := range []string{"1", "2"} {
+		if err := l.SetRegistryCache(p, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// "1" becomes the most recently used, so "2" goes first
+	if e, _ := l.GetRegistryCache("1"); e == nil {
+		t.Fatal("1 is missing")
+	}
+	if err := l.SetRegistryCache("3", entry); err != nil {
+		t.Fatal(err)
+	}
+
+	for p, want := range map[string]bool{"1": true, "2": false, "3": true} {
+		if e, _ := l.GetRegistryCache(p); (e != nil) != want {
+			t.Errorf("%s cached = %v, want %v", p, e != nil, want)
+		}
+	}
+}
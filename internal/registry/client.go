@@ -0,0 +1,218 @@
+package registry
+
+import (
+	"context"
+	"effective_mobile_testing/internal/config"
+	"effective_mobile_testing/internal/model"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxBodySize caps the response read from the registry.
+const maxBodySize = 1 << 20
+
+// ErrNotConfigured is returned when ANOTHER_API_URL is missing or invalid.
+var ErrNotConfigured = errors.New("passport registry url is not configured")
+
+// statusError is a response with an unexpected status code.
+type statusError struct {
+	code       int
+	retryAfter time.Duration
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("registry returned status %d", e.code)
+}
+
+// Client looks up persons in the passport registry. Every attempt has a timeout, failed
+// attempts caused by the network or the registry (5xx, 429) are retried with jittered
+// exponential backoff, and a circuit breaker stops calls while the registry keeps failing.
+type Client struct {
+	baseURL     string
+	client      *http.Client
+	maxAttempts int
+	baseDelay   time.Duration
+	breaker     *breaker
+}
+
+func NewClient() *Client {
+	return &Client{
+		baseURL:     config.GetRegistryURL(),
+		client:      &http.Client{Timeout: config.GetRegistryTimeout()},
+		maxAttempts: config.GetRegistryMaxAttempts(),
+		baseDelay:   config.GetRegistryRetryDelay(),
+		breaker:     newBreaker(config.GetRegistryBreakerThreshold(), config.GetRegistryBreakerCooldown()),
+	}
+}
+
+// GetPerson returns the registry data of the passport. Errors are ErrPassportNotFound, ErrInvalidRequest,
+// ErrUnavailable (as *UnavailableError), ErrMalformedResponse or ErrNotConfigured.
+func (c *Client) GetPerson(ctx context.Context, passportSerie, passportNumber string) (model.UserFromAPI, error) {
+	if c.baseURL == "" {
+		return model.UserFromAPI{}, ErrNotConfigured
+	}
+
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return model.UserFromAPI{}, fmt.Errorf("%w: %w", ErrNotConfigured, err)
+	}
+
+	q := u.Query()
+	q.Set("passportSerie", passportSerie)
+	q.Set("passportNumber", passportNumber)
+	u.RawQuery = q.Encode()
+
+	if ok, wait := c.breaker.allow(); !ok {
+		return model.UserFromAPI{}, &UnavailableError{RetryAfter: wait, Err: errors.New("circuit breaker is open")}
+	}
+
+	var user model.UserFromAPI
+
+	for attempt := 1; ; attempt++ {
+		user, err = c.get(ctx, u.String())
+		if err == nil {
+			c.breaker.success()
+			return user, nil
+		}
+
+		if !retryable(ctx, err) {
+			if ctx.Err() != nil {
+				c.breaker.release()
+			} else {
+				// the registry answered, it is up
+				c.breaker.success()
+			}
+			return model.UserFromAPI{}, classify(err)
+		}
+
+		if attempt >= c.maxAttempts {
+			break
+		}
+
+		delay := backoff(c.baseDelay, attempt)
+		slog.Warn("registry request failed, retrying",
+			slog.Int("attempt", attempt),
+			slog.Duration("delay", delay),
+			slog.String("err", err.Error()),
+		)
+
+		select {
+		case <-ctx.Done():
+			c.breaker.release()
+			return model.UserFromAPI{}, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+
+	wait := c.breaker.failure()
+
+	var se *statusError
+	if errors.As(err, &se) && se.retryAfter > wait {
+		wait = se.retryAfter
+	}
+
+	return model.UserFromAPI{}, &UnavailableError{RetryAfter: max(wait, time.Second), Err: err}
+}
+
+func (c *Client) get(ctx context.Context, u string) (model.UserFromAPI, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return model.UserFromAPI{}, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// the query holds the passport, keep it out of errors and logs
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			ue.URL = c.baseURL
+		}
+		return model.UserFromAPI{}, err
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return model.UserFromAPI{}, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return model.UserFromAPI{}, &statusError{code: resp.StatusCode, retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	}
+
+	var user model.UserFromAPI
+
+	if err := json.Unmarshal(body, &user); err != nil {
+		return model.UserFromAPI{}, fmt.Errorf("%w: %w", ErrMalformedResponse, err)
+	}
+
+	if strings.TrimSpace(user.Surname) == "" || strings.TrimSpace(user.Name) == "" {
+		return model.UserFromAPI{}, fmt.Errorf("%w: empty surname or name", ErrMalformedResponse)
+	}
+
+	return user, nil
+}
+
+// classify turns a final answer of the registry into one of the package errors.
+func classify(err error) error {
+	var se *statusError
+	if !errors.As(err, &se) {
+		return err
+	}
+
+	switch {
+	case se.code == http.StatusNotFound:
+		return fmt.Errorf("%w: %w", ErrPassportNotFound, err)
+	case se.code == http.StatusBadRequest || se.code == http.StatusUnprocessableEntity:
+		return fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	default:
+		// auth or other unexpected answers, we can't get data from the registry
+		return &UnavailableError{RetryAfter: max(se.retryAfter, time.Second), Err: err}
+	}
+}
+
+// retryable tells whether another attempt may succeed: network errors and timeouts,
+// 5xx and 429. Cancelled requests and other answers of the registry are final.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code >= http.StatusInternalServerError || se.code == http.StatusTooManyRequests
+	}
+
+	var ue *url.Error
+
+	return errors.As(err, &ue)
+}
+
+// parseRetryAfter reads the seconds form of Retry-After, 0 when missing.
+func parseRetryAfter(s string) time.Duration {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0
+	}
+
+	return time.Duration(n) * time.Second
+}
+
+// backoff is a random delay between half and all of baseDelay * 2^(attempt-1), so clients don't retry in step.
+func backoff(baseDelay time.Duration, attempt int) time.Duration {
+	ceiling := baseDelay << (attempt - 1)
+
+	return ceiling/2 + time.Duration(rand.Int63n(int64(ceiling/2)+1))
+}
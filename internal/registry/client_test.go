@@ -0,0 +1,142 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	return &Client{
+		baseURL:     srv.URL,
+		client:      &http.Client{Timeout: 50 * time.Millisecond},
+		maxAttempts: 3,
+		baseDelay:   time.Millisecond,
+		breaker:     newBreaker(100, time.Minute),
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		status   int
+		body     string
+		want     error
+		attempts int32
+	}{
+		{http.StatusOK, `{"surname":"Иванов","name":"Иван"}`, nil, 1},
+		{http.StatusOK, `{"surname":"Иванов","name":" "}`, ErrMalformedResponse, 1},
+		{http.StatusOK, `{"surname":`, ErrMalformedResponse, 1},
+		{http.StatusNotFound, "", ErrPassportNotFound, 1},
+		{http.StatusBadRequest, "", ErrInvalidRequest, 1},
+		{http.StatusUnprocessableEntity, "", ErrInvalidRequest, 1},
+		{http.StatusForbidden, "", ErrUnavailable, 1},
+		{http.StatusInternalServerError, "", ErrUnavailable, 3},
+		{http.StatusServiceUnavailable, "", ErrUnavailable, 3},
+		{http.StatusTooManyRequests, "", ErrUnavailable, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d %s", tt.status, tt.body), func(t *testing.T) {
+			var attempts atomic.Int32
+
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				attempts.Add(1)
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			})
+
+			_, err := c.GetPerson(context.Background(), "1234", "567890")
+			if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+
+			if got := attempts.Load(); got != tt.attempts {
+				t.Errorf("attempts = %d, want %d", got, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestRetryAfterHeader(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	_, err := c.GetPerson(context.Background(), "1234", "567890")
+
+	var unavailable *UnavailableError
+	if !errors.As(err, &unavailable) {
+		t.Fatalf("err = %v, want *UnavailableError", err)
+	}
+
+	if unavailable.RetryAfter != 7*time.Second {
+		t.Errorf("RetryAfter = %v, want 7s", unavailable.RetryAfter)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	var attempts atomic.Int32
+
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		time.Sleep(200 * time.Millisecond)
+	})
+
+	u := c.baseURL + "?passportSerie=1234&passportNumber=567890"
+
+	_, err := c.get(context.Background(), u)
+	if !retryable(context.Background(), err) {
+		t.Errorf("timeout %v is not retryable", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if retryable(ctx, err) {
+		t.Error("retryable after the context is done")
+	}
+
+	for code, want := range map[int]bool{
+		http.StatusInternalServerError: true,
+		http.StatusBadGateway:          true,
+		http.StatusTooManyRequests:     true,
+		http.StatusNotFound:            false,
+		http.StatusBadRequest:          false,
+		http.StatusUnauthorized:        false,
+	} {
+		if got := retryable(context.Background(), &statusError{code: code}); got != want {
+			t.Errorf("retryable(%d) = %v, want %v", code, got, want)
+		}
+	}
+
+	if retryable(context.Background(), fmt.Errorf("%w: bad json", ErrMalformedResponse)) {
+		t.Error("malformed response is retryable")
+	}
+}
+
+func TestErrorsKeepPassportOutOfURL(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	})
+	c.maxAttempts = 1
+
+	_, err := c.GetPerson(context.Background(), "1234", "567890")
+	if err == nil {
+		t.Fatal("expected timeout")
+	}
+
+	if s := err.Error(); strings.Contains(s, "567890") || strings.Contains(s, "passportNumber") {
+		t.Errorf("error leaks the passport: %s", s)
+	}
+}
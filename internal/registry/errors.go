@@ -0,0 +1,40 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrPassportNotFound means the registry has no person with the passport.
+	ErrPassportNotFound = errors.New("passport not found in the registry")
+	// ErrInvalidRequest means the registry rejected the passport as invalid.
+	ErrInvalidRequest = errors.New("registry rejected the request")
+	// ErrUnavailable means the registry can't answer now, the same request may succeed later.
+	ErrUnavailable = errors.New("passport registry is unavailable")
+	// ErrMalformedResponse means the registry answered with data we can't use.
+	ErrMalformedResponse = errors.New("malformed registry response")
+)
+
+// UnavailableError is ErrUnavailable with a hint when to try again.
+type UnavailableError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *UnavailableError) Error() string {
+	if e.Err == nil {
+		return ErrUnavailable.Error()
+	}
+
+	return fmt.Sprintf("%s: %s", ErrUnavailable, e.Err)
+}
+
+func (e *UnavailableError) Is(target error) bool {
+	return target == ErrUnavailable
+}
+
+func (e *UnavailableError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,108 @@
+package registry
+
+import (
+	"container/list"
+	"effective_mobile_testing/internal/model"
+	"sync"
+	"time"
+)
+
+// lru is the in-memory Store. It keeps at most size passports and drops the least recently used first.
+type lru struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // front is the most recently used
+	items map[string]*list.Element
+}
+
+type lruItem struct {
+	passport string
+	entry    model.RegistryCacheEntry
+}
+
+func newLRU(size int) *lru {
+	return &lru{size: size, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (l *lru) GetRegistryCache(passport string) (*model.RegistryCacheEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.items[passport]
+	if !ok {
+		return nil, nil
+	}
+
+	item := e.Value.(*lruItem)
+	if !time.Now().Before(item.entry.ExpiresAt) {
+		l.remove(e)
+		return nil, nil
+	}
+
+	l.order.MoveToFront(e)
+	entry := item.entry
+
+	return &entry, nil
+}
+
+func (l *lru) SetRegistryCache(passport string, entry model.RegistryCacheEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e, ok := l.items[passport]; ok {
+		e.Value.(*lruItem).entry = entry
+		l.order.MoveToFront(e)
+		return nil
+	}
+
+	l.items[passport] = l.order.PushFront(&lruItem{passport: passport, entry: entry})
+
+	for l.order.Len() > l.size {
+		l.remove(l.order.Back())
+	}
+
+	return nil
+}
+
+func (l *lru) DeleteRegistryCache(passport string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e, ok := l.items[passport]; ok {
+		l.remove(e)
+	}
+
+	return nil
+}
+
+func (l *lru) PurgeRegistryCache() (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	var n int64
+
+	for e := l.order.Front(); e != nil; {
+		next := e.Next()
+		if !now.Before(e.Value.(*lruItem).entry.ExpiresAt) {
+			l.remove(e)
+			n++
+		}
+		e = next
+	}
+
+	return n, nil
+}
+
+func (l *lru) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.order.Len()
+}
+
+func (l *lru) remove(e *list.Element) {
+	l.order.Remove(e)
+	delete(l.items, e.Value.(*lruItem).passport)
+}
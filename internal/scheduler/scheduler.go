@@ -0,0 +1,29 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Every runs job every interval until ctx is done. A failed run is logged and
+// the job is tried again on the next tick.
+func Every(ctx context.Context, interval time.Duration, name string, job func(ctx context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		start := time.Now()
+		if err := job(ctx); err != nil {
+			slog.Error("scheduled job failed", slog.String("job", name), slog.String("err", err.Error()))
+			continue
+		}
+		slog.Debug("scheduled job finished", slog.String("job", name), slog.Duration("took", time.Since(start)))
+	}
+}
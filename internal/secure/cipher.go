@@ -0,0 +1,141 @@
+package secure
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const keySize = 32
+
+var (
+	ErrNoKeys       = errors.New("no encryption keys configured")
+	ErrNoIndexKey   = errors.New("no blind index key configured")
+	ErrUnknownKey   = errors.New("unknown encryption key")
+	ErrInvalidKey   = errors.New("encryption keys must be 32 bytes, base64 encoded")
+	ErrInvalidValue = errors.New("invalid encrypted value")
+)
+
+// Cipher encrypts short values with AES-256-GCM. Every value carries the id of its key
+// ("<key id>:<base64 nonce+ciphertext>"), so old keys keep decrypting after the active one changes.
+// The blind index is a keyed HMAC of the value, usable for equality lookups and unique indexes.
+type Cipher struct {
+	keys     map[string]cipher.AEAD
+	active   string
+	indexKey []byte
+}
+
+// NewCipher takes base64 encoded keys by id, the id of the key used for new values and the blind index key.
+func NewCipher(keys map[string]string, active, indexKey string) (*Cipher, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	if indexKey == "" {
+		return nil, ErrNoIndexKey
+	}
+
+	c := &Cipher{keys: make(map[string]cipher.AEAD, len(keys)), active: active}
+
+	for id, encoded := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("%w: key id %q", ErrInvalidKey, id)
+		}
+
+		key, err := decodeKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+
+		c.keys[id] = aead
+	}
+
+	if _, ok := c.keys[active]; !ok {
+		return nil, fmt.Errorf("%w: active key %q", ErrUnknownKey, active)
+	}
+
+	idx, err := decodeKey(indexKey)
+	if err != nil {
+		return nil, fmt.Errorf("index key: %w", err)
+	}
+	c.indexKey = idx
+
+	return c, nil
+}
+
+// ActiveKey is the id of the key used by Encrypt.
+func (c *Cipher) ActiveKey() string {
+	return c.active
+}
+
+func (c *Cipher) Encrypt(plain string) (string, error) {
+	aead := c.keys[c.active]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(plain), []byte(c.active))
+
+	return c.active + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (c *Cipher) Decrypt(value string) (string, error) {
+	id, data, ok := strings.Cut(value, ":")
+	if !ok {
+		return "", ErrInvalidValue
+	}
+
+	aead, ok := c.keys[id]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownKey, id)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(data)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", ErrInvalidValue
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+
+	plain, err := aead.Open(nil, nonce, ciphertext, []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidValue, err)
+	}
+
+	return string(plain), nil
+}
+
+// BlindIndex returns the hex HMAC-SHA256 of the value. The same value always gives the same index.
+func (c *Cipher) BlindIndex(value string) string {
+	mac := hmac.New(sha256.New, c.indexKey)
+	mac.Write([]byte(value))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != keySize {
+		return nil, ErrInvalidKey
+	}
+
+	return key, nil
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"effective_mobile_testing/internal/auth"
+	"effective_mobile_testing/internal/service/repository"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Permissions are checked here rather than per route, so every transport gets them.
+// Admins may do everything. Managers may read persons and the time of their teams. Employees
+// may track and read only their own time. Errors wrap auth.ErrForbidden with the reason.
+
+func forbidden(reason string) error {
+	return fmt.Errorf("%w: %s", auth.ErrForbidden, reason)
+}
+
+// requireAdmin guards changes to persons and everything not tied to one person.
+func requireAdmin(ctx context.Context) error {
+	if !auth.HasRole(ctx, auth.RoleAdmin) {
+		return forbidden("admin role required")
+	}
+
+	return nil
+}
+
+// requireManager guards reading data that isn't tied to one person, like the list of teams.
+func requireManager(ctx context.Context) error {
+	if !auth.HasRole(ctx, auth.RoleManager) {
+		return forbidden("manager or admin role required")
+	}
+
+	return nil
+}
+
+// managerScope returns the person whose teams limit the persons a manager may list, 0 for admins.
+func managerScope(ctx context.Context) (int64, error) {
+	p, _ := auth.FromContext(ctx)
+
+	if p.HasRole(auth.RoleAdmin) {
+		return 0, nil
+	}
+	if !p.HasRole(auth.RoleManager) {
+		return 0, forbidden("manager or admin role required")
+	}
+	if p.PersonID == 0 {
+		return 0, forbidden("manager is not linked to a person")
+	}
+
+	return p.PersonID, nil
+}
+
+// requireTracking allows starting and stopping tasks of the caller's own person.
+func requireTracking(ctx context.Context, personID int64) error {
+	p, _ := auth.FromContext(ctx)
+
+	if p.HasRole(auth.RoleAdmin) {
+		return nil
+	}
+	if !p.HasRole(auth.RoleEmployee) && !p.HasRole(auth.RoleManager) {
+		return forbidden("employee, manager or admin role required")
+	}
+	if !p.IsPerson(personID) {
+		return forbidden("only your own time can be tracked")
+	}
+
+	return nil
+}
+
+// requireReport allows reading the time of personID to that person and to the managers of their teams.
+// It returns the id to read: the survivor when personID was merged.
+func (s *UserTaskService) requireReport(ctx context.Context, personID int64) (int64, error) {
+	p, _ := auth.FromContext(ctx)
+
+	if !p.HasRole(auth.RoleAdmin) && !p.HasRole(auth.RoleManager) && !p.HasRole(auth.RoleEmployee) {
+		return 0, forbidden("employee, manager or admin role required")
+	}
+
+	// a merged id reads the survivor, so the survivor is what is checked. A missing person is
+	// checked as is, so callers without access get 403 and can't probe which ids exist
+	resolved, err := s.repo.ResolvePersonID(personID)
+	switch {
+	case err == nil:
+		personID = resolved
+	case errors.Is(err, repository.ErrUserNotFound) && !p.HasRole(auth.RoleAdmin):
+	default:
+		return 0, err
+	}
+
+	if p.HasRole(auth.RoleAdmin) {
+		return personID, nil
+	}
+	if p.IsPerson(personID) {
+		return personID, nil
+	}
+	if !p.HasRole(auth.RoleManager) {
+		return 0, forbidden("employees can see only their own time")
+	}
+
+	ok, err := s.repo.ManagesPerson(p.PersonID, personID, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, forbidden("the user is not in a team you manage")
+	}
+
+	return personID, nil
+}
+
+// requireTeam allows reading a team to admins and to its managers.
+func (s *UserTaskService) requireTeam(ctx context.Context, teamID int64) error {
+	p, _ := auth.FromContext(ctx)
+
+	if p.HasRole(auth.RoleAdmin) {
+		return nil
+	}
+	if !p.HasRole(auth.RoleManager) {
+		return forbidden("manager or admin role required")
+	}
+
+	ok, err := s.repo.ManagesTeam(p.PersonID, teamID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return forbidden("you don't manage this team")
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"effective_mobile_testing/internal/auth"
+	"effective_mobile_testing/internal/config"
+	"effective_mobile_testing/internal/mask"
+	"effective_mobile_testing/internal/model"
+	"log/slog"
+	"strconv"
+)
+
+// auditAccess records a read of personal data. Reads fail when the record can't be written,
+// so no personal data is returned without a trace.
+func (s *UserTaskService) auditAccess(ctx context.Context, action string, filter map[string]string, personIDs []int64) error {
+	if err := s.repo.AddAccessAudit(auth.Actor(ctx), action, filter, personIDs); err != nil {
+		slog.Error("can't write access audit", slog.String("action", action), slog.String("err", err.Error()))
+		return err
+	}
+
+	return nil
+}
+
+// GetAccessAudit returns the access audit, newest first. Admin only.
+func (s *UserTaskService) GetAccessAudit(ctx context.Context, filter model.AccessAuditFilter) (*model.AccessAuditPage, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	filter.Limit = config.GetPageSize(filter.Limit)
+
+	page, err := s.repo.ListAccessAudit(filter)
+	if err != nil {
+		slog.Error("can't get access audit", slog.String("err", err.Error()))
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// userFilterAudit keeps the filter fields that were set. The audit can't be erased, so personal
+// data in the filter is masked: names and the search query to first letters, the address to the city.
+func userFilterAudit(filter model.UserFilter) map[string]string {
+	f := make(map[string]string)
+
+	set := func(name, value string) {
+		if value != "" {
+			f[name] = value
+		}
+	}
+
+	if filter.ID != 0 {
+		f["id"] = strconv.FormatInt(filter.ID, 10)
+	}
+	for _, ff := range []struct {
+		name   string
+		filter model.FieldFilter
+		mask   func(string) string
+	}{
+		{"surname", filter.Surname, mask.Name},
+		{"name", filter.Name, mask.Name},
+		{"patronymic", filter.Patronymic, mask.Name},
+		{"address", filter.Address, mask.Address},
+	} {
+		if ff.filter.Value != "" {
+			f[ff.name] = ff.mask(ff.filter.Value)
+			set(ff.name+"_match", ff.filter.Mode)
+		}
+	}
+	if filter.PassportNumber != "" {
+		f["passport_number"] = mask.Passport(filter.PassportNumber)
+	}
+	set("q", mask.Name(filter.Query))
+	set("sort", filter.Sort)
+	// the cursor carries values of the last row, like its surname
+	if filter.Cursor != "" {
+		f["cursor"] = "***"
+	}
+	if filter.Offset != 0 {
+		f["offset"] = strconv.Itoa(filter.Offset)
+	}
+	if filter.IncludeDeleted {
+		f["include_deleted"] = "true"
+	}
+
+	return f
+}
+
+func userIDs(users []model.User) []int64 {
+	ids := make([]int64, len(users))
+	for i, u := range users {
+		ids[i] = u.ID
+	}
+
+	return ids
+}
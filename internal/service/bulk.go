@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"effective_mobile_testing/internal/config"
+	"effective_mobile_testing/internal/model"
+	"effective_mobile_testing/internal/registry"
+	"effective_mobile_testing/internal/service/repository"
+	"effective_mobile_testing/internal/validators"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrEmptyBulk   = errors.New("passport list is empty")
+	ErrJobNotFound = errors.New("job not found")
+)
+
+// bulkJobs keeps jobs in the memory of the instance running them, a restart loses them.
+// Finished jobs expire ttl after they finish and are dropped when the next job starts.
+type bulkJobs struct {
+	mu   sync.RWMutex
+	jobs map[string]*model.BulkJob
+	ttl  time.Duration
+}
+
+func newBulkJobs() *bulkJobs {
+	return &bulkJobs{jobs: make(map[string]*model.BulkJob), ttl: config.GetBulkJobTTL()}
+}
+
+func (b *bulkJobs) add(job *model.BulkJob) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.evict(time.Now())
+	b.jobs[job.ID] = job
+}
+
+// evict drops finished jobs older than ttl. The caller holds the write lock.
+func (b *bulkJobs) evict(now time.Time) {
+	for id, job := range b.jobs {
+		if b.expired(job, now) {
+			delete(b.jobs, id)
+		}
+	}
+}
+
+func (b *bulkJobs) expired(job *model.BulkJob, now time.Time) bool {
+	return job.FinishedAt != nil && now.Sub(*job.FinishedAt) > b.ttl
+}
+
+func (b *bulkJobs) setRow(id string, i int, row model.BulkJobRow) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	job, ok := b.jobs[id]
+	if !ok {
+		return
+	}
+
+	job.Rows[i] = row
+	job.Processed++
+}
+
+func (b *bulkJobs) finish(id string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	job, ok := b.jobs[id]
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	job.Status = model.BulkJobDone
+	job.FinishedAt = &now
+}
+
+// get returns a copy of the job so callers can read it while workers keep writing rows.
+func (b *bulkJobs) get(id string) (*model.BulkJob, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	job, ok := b.jobs[id]
+	if !ok || b.expired(job, time.Now()) {
+		return nil, false
+	}
+
+	cp := *job
+	cp.Rows = append([]model.BulkJobRow(nil), job.Rows...)
+
+	return &cp, true
+}
+
+func (s *UserTaskService) StartBulkCreate(ctx context.Context, passportNumbers []string) (*model.BulkJob, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	if len(passportNumbers) == 0 {
+		return nil, ErrEmptyBulk
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		slog.Error("can't generate job id", slog.String("err", err.Error()))
+		return nil, err
+	}
+
+	job := &model.BulkJob{
+		ID:        id,
+		Status:    model.BulkJobRunning,
+		Total:     len(passportNumbers),
+		CreatedAt: time.Now(),
+		Rows:      make([]model.BulkJobRow, len(passportNumbers)),
+	}
+
+	for i, p := range passportNumbers {
+		job.Rows[i] = model.BulkJobRow{PassportNumber: p, Status: model.BulkRowPending}
+	}
+
+	s.jobs.add(job)
+	// the job outlives the request, but keeps its caller for the history
+	go s.runBulkCreate(context.WithoutCancel(ctx), id, passportNumbers)
+
+	snapshot, _ := s.jobs.get(id)
+
+	return snapshot, nil
+}
+
+func (s *UserTaskService) GetBulkJob(ctx context.Context, id string) (*model.BulkJob, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	job, ok := s.jobs.get(id)
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+
+	return job, nil
+}
+
+func (s *UserTaskService) runBulkCreate(ctx context.Context, id string, passportNumbers []string) {
+	sem := make(chan struct{}, config.GetBulkConcurrency())
+	var wg sync.WaitGroup
+
+	for i, p := range passportNumbers {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int, p string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			s.jobs.setRow(id, i, s.createBulkRow(ctx, p))
+		}(i, p)
+	}
+
+	wg.Wait()
+	s.jobs.finish(id)
+	slog.Debug("bulk job finished", slog.String("id", id))
+}
+
+func (s *UserTaskService) createBulkRow(ctx context.Context, passportNumber string) model.BulkJobRow {
+	row := model.BulkJobRow{PassportNumber: passportNumber}
+
+	passport := strings.Fields(passportNumber)
+	if !validators.CheckLenPassport(passport) {
+		row.Status = model.BulkRowInvalidPassport
+		return row
+	}
+
+	userFromAPI, err := s.GetUserData(ctx, passport[0], passport[1])
+	if err != nil {
+		// the registry doesn't know or accept the passport, retrying the row won't help
+		if errors.Is(err, registry.ErrPassportNotFound) || errors.Is(err, registry.ErrInvalidRequest) {
+			row.Status = model.BulkRowInvalidPassport
+			row.Error = err.Error()
+			return row
+		}
+		row.Status = model.BulkRowAPIFailure
+		row.Error = err.Error()
+		return row
+	}
+
+	user, err := s.CreateUser(ctx, passport[0]+" "+passport[1], userFromAPI)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserExists) {
+			row.Status = model.BulkRowAlreadyExists
+			return row
+		}
+		row.Status = model.BulkRowFailed
+		row.Error = err.Error()
+		return row
+	}
+
+	row.Status = model.BulkRowCreated
+	row.UserID = user.ID
+
+	return row
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
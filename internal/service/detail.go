@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+
+	"effective_mobile_testing/internal/model"
+	"effective_mobile_testing/internal/service/repository"
+	"errors"
+	"log/slog"
+	"strconv"
+
+	"time"
+)
+
+const recentTasksLimit = 5
+
+// GetUserDetail returns the user with a tracking summary for today, this week (from Monday) and this month.
+func (s *UserTaskService) GetUserDetail(ctx context.Context, id int64) (*model.UserDetail, error) {
+	personID, err := s.requireReport(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUser(personID)
+	if err != nil {
+		if !errors.Is(err, repository.ErrUserNotFound) {
+			slog.Error("can't get user", slog.String("err", err.Error()))
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	weekStart := dayStart.AddDate(0, 0, -(int(now.Weekday())+6)%7)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	summary, err := s.repo.GetTrackingSummary(user.ID, now, dayStart, weekStart, monthStart, recentTasksLimit)
+	if err != nil {
+		slog.Error("can't get tracking summary", slog.String("err", err.Error()))
+		return nil, err
+	}
+
+	if err := s.auditAccess(ctx, model.AccessUserDetail, map[string]string{"id": strconv.FormatInt(id, 10)}, []int64{user.ID}); err != nil {
+		return nil, err
+	}
+
+	return &model.UserDetail{User: *user, Tracking: *summary}, nil
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"effective_mobile_testing/internal/auth"
+	"effective_mobile_testing/internal/model"
+	"effective_mobile_testing/internal/service/repository"
+	"errors"
+	"log/slog"
+	"math"
+	"sort"
+)
+
+const (
+	// similarity thresholds for a field to count as a match
+	duplicateNameThreshold    = 0.8
+	duplicateAddressThreshold = 0.5
+
+	// a candidate needs at least this many matching fields
+	duplicateMinReasons = 2
+)
+
+// FindDuplicates returns persons that probably are the same human as the person id, best match first.
+// Name, address and passport number are compared; one matching field alone is not enough.
+func (s *UserTaskService) FindDuplicates(ctx context.Context, id int64) ([]model.DuplicateCandidate, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	candidates, err := s.repo.FindDuplicateCandidates(id)
+	if err != nil {
+		if !errors.Is(err, repository.ErrUserNotFound) {
+			slog.Error("can't find duplicate candidates", slog.String("err", err.Error()))
+		}
+		return nil, err
+	}
+
+	duplicates := []model.DuplicateCandidate{}
+
+	for _, c := range candidates {
+		c.Reasons = []string{}
+		if c.NameSimilarity >= duplicateNameThreshold {
+			c.Reasons = append(c.Reasons, "name")
+		}
+		if c.AddressSimilarity >= duplicateAddressThreshold {
+			c.Reasons = append(c.Reasons, "address")
+		}
+		if c.SamePassport {
+			c.Reasons = append(c.Reasons, "passport")
+		}
+
+		if len(c.Reasons) < duplicateMinReasons {
+			continue
+		}
+
+		passport := 0.0
+		if c.SamePassport {
+			passport = 1
+		}
+		c.Score = math.Round((c.NameSimilarity*0.5+c.AddressSimilarity*0.3+passport*0.2)*100) / 100
+
+		duplicates = append(duplicates, c)
+	}
+
+	sort.SliceStable(duplicates, func(i, j int) bool {
+		return duplicates[i].Score > duplicates[j].Score
+	})
+
+	return duplicates, nil
+}
+
+// MergeUsers keeps survivorID and turns duplicateID into a redirect to it.
+func (s *UserTaskService) MergeUsers(ctx context.Context, survivorID, duplicateID int64) (*model.User, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.MergeUsers(auth.Actor(ctx), survivorID, duplicateID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) || errors.Is(err, repository.ErrSameUser) || errors.Is(err, repository.ErrMembershipOverlaps) {
+			return nil, err
+		}
+
+		slog.Error("can't merge users", slog.String("err", err.Error()))
+		return nil, err
+	}
+
+	return user, nil
+}
@@ -1,23 +1,28 @@
 package service
 
 import (
+	"context"
 	"database/sql"
+	"effective_mobile_testing/internal/auth"
+	"effective_mobile_testing/internal/config"
 	"effective_mobile_testing/internal/model"
 	"effective_mobile_testing/internal/service/repository"
-	"encoding/json"
+	"effective_mobile_testing/internal/validators"
 	"errors"
 	"fmt"
 	_ "github.com/joho/godotenv"
-	"io"
 	"log/slog"
-	"net/http"
-	"os"
+	"time"
 )
 
-func (s *UserTaskService) CreateUser(passportNumber string, user model.UserFromAPI) (*model.User, error) {
-	createdUser, err := s.repo.CreateUser(user.Surname, user.Name, user.Patronymic, user.Address, passportNumber)
+func (s *UserTaskService) CreateUser(ctx context.Context, passportNumber string, user model.UserFromAPI) (*model.User, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	createdUser, err := s.repo.CreateUser(auth.Actor(ctx), user.Surname, user.Name, user.Patronymic, user.Address, passportNumber)
 	if err != nil {
-		if errors.As(err, &repository.ErrUserExists) {
+		if errors.Is(err, repository.ErrUserExists) {
 			return nil, repository.ErrUserExists
 		}
 
@@ -28,49 +33,39 @@ func (s *UserTaskService) CreateUser(passportNumber string, user model.UserFromA
 	return createdUser, nil
 }
 
-func (s *UserTaskService) GetUserData(passportSerie, passportNumber string) (model.UserFromAPI, error) {
-	baseUrl := os.Getenv("ANOTHER_API_URL")
-	if baseUrl == "" {
-		slog.Error("can't get ANOTHER_API_URL environment variable")
-		return model.UserFromAPI{}, errors.New("can't get ANOTHER_API_URL")
-	}
-
-	fullUrl := fmt.Sprintf("%s?passportSerie=%s&passportNumber=%s", baseUrl, passportSerie, passportNumber)
-
-	req, err := http.NewRequest(http.MethodGet, fullUrl, nil)
-	if err != nil {
-		slog.Error("error creating new request:", slog.String("err", err.Error()))
+func (s *UserTaskService) GetUserData(ctx context.Context, passportSerie, passportNumber string) (model.UserFromAPI, error) {
+	if err := requireAdmin(ctx); err != nil {
 		return model.UserFromAPI{}, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	user, err := s.registry.GetPerson(ctx, passportSerie, passportNumber)
 	if err != nil {
-		slog.Error("error making request", slog.String("err", err.Error()))
+		slog.Error("can't get user from registry", slog.String("err", err.Error()))
 		return model.UserFromAPI{}, err
 	}
 
-	defer resp.Body.Close()
+	return user, nil
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		slog.Error("error reading response body:", slog.String("err", err.Error()))
-		return model.UserFromAPI{}, err
+// GetRegistryCacheStats returns the registry cache hits and misses of this instance. Admin only.
+func (s *UserTaskService) GetRegistryCacheStats(ctx context.Context) (*model.RegistryCacheStats, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
 	}
 
-	var user model.UserFromAPI
-
-	if err := json.Unmarshal(body, &user); err != nil {
-		slog.Error("error parsing response body", slog.String("err", err.Error()))
-		return model.UserFromAPI{}, err
-	}
+	stats := s.registry.Stats()
 
-	return user, nil
+	return &stats, nil
 }
 
-func (s *UserTaskService) StartTracking(req model.RequestStartTracking) error {
+func (s *UserTaskService) StartTracking(ctx context.Context, req model.RequestStartTracking) error {
+	if err := requireTracking(ctx, req.UserID); err != nil {
+		return err
+	}
+
 	if err := s.repo.StartTask(req.UserID, req.TaskName); err != nil {
-		if errors.As(err, &repository.ErrUserNotFound) {
-			return fmt.Errorf("%v", repository.ErrUserNotFound)
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return repository.ErrUserNotFound
 		}
 		slog.Error("can't start tracking:", slog.String("err", err.Error()))
 		return err
@@ -79,7 +74,11 @@ func (s *UserTaskService) StartTracking(req model.RequestStartTracking) error {
 	return nil
 }
 
-func (s *UserTaskService) StopTracking(req model.RequestStopTracking) error {
+func (s *UserTaskService) StopTracking(ctx context.Context, req model.RequestStopTracking) error {
+	if err := requireTracking(ctx, req.UserID); err != nil {
+		return err
+	}
+
 	if err := s.repo.StopTask(req.UserID, req.TaskName); err != nil {
 		if err != sql.ErrNoRows {
 			return fmt.Errorf("%v", repository.ErrUserNotFound)
@@ -91,37 +90,66 @@ func (s *UserTaskService) StopTracking(req model.RequestStopTracking) error {
 	return nil
 }
 
-func (s *UserTaskService) GetLaborCosts(userID int64) ([]model.ResponseLobarCost, error) {
-	resp, err := s.repo.GetLaborCosts(userID)
+func (s *UserTaskService) GetLaborCosts(ctx context.Context, filter model.LaborCostFilter) (*model.LaborCostPage, error) {
+	personID, err := s.requireReport(ctx, filter.UserID)
 	if err != nil {
-		if err != sql.ErrNoRows {
-			return nil, fmt.Errorf("%v", repository.ErrUserNotFound)
+		return nil, err
+	}
+	filter.UserID = personID
+
+	filter.Limit = config.GetPageSize(filter.Limit)
+
+	resp, err := s.repo.GetLaborCosts(filter)
+	if err != nil {
+		if !errors.Is(err, repository.ErrUserNotFound) && !errors.Is(err, repository.ErrInvalidCursor) {
+			slog.Error("cant' get labor costs", slog.String("err", err.Error()))
 		}
-		slog.Error("cant' get labor costs", slog.String("err", err.Error()))
 		return nil, err
 	}
 
-	for i := range resp {
-		hours := resp[i].DurationMinutes / 60
-		resp[i].DurationHours = hours
-		resp[i].DurationMinutes = resp[i].DurationMinutes % 60
+	for i := range resp.Costs {
+		hours := resp.Costs[i].DurationMinutes / 60
+		resp.Costs[i].DurationHours = hours
+		resp.Costs[i].DurationMinutes = resp.Costs[i].DurationMinutes % 60
 	}
 
 	return resp, nil
 }
 
-func (s *UserTaskService) GetUserByFilters(limit, offset int, user model.User) (*[]model.User, error) {
-	users, err := s.repo.GetUserByFilters(limit, offset, user.ID, user.Surname, user.Name, user.Patronymic, user.Address, user.PassportNumber)
+// GetUserByFilters lists everyone to admins, and to managers only themselves and the members of their teams.
+func (s *UserTaskService) GetUserByFilters(ctx context.Context, filter model.UserFilter) (*model.UserPage, error) {
+	managerID, err := managerScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter.ManagerID = managerID
+
+	filter.Limit = config.GetPageSize(filter.Limit)
+
+	users, err := s.repo.GetUserByFilters(filter)
 	if err != nil {
 		slog.Error("can't get user by filters", slog.String("err", err.Error()))
 		return nil, err
 	}
 
+	if err := s.auditAccess(ctx, model.AccessUsersList, userFilterAudit(filter), userIDs(users.Users)); err != nil {
+		return nil, err
+	}
+
 	return users, nil
 }
 
-func (s *UserTaskService) DeleteUser(id int64) error {
-	if err := s.repo.DeleteUser(id); err != nil {
+func (s *UserTaskService) DeleteUser(ctx context.Context, id, version int64) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteUser(auth.Actor(ctx), id, version); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) || errors.Is(err, repository.ErrVersionMismatch) {
+			return err
+		}
+
 		slog.Error("can't delete user", slog.String("err", err.Error()))
 		return err
 	}
@@ -129,11 +157,20 @@ func (s *UserTaskService) DeleteUser(id int64) error {
 	return nil
 }
 
-func (s *UserTaskService) UpdateUser(id int64, user model.UserUpdateRequest) (*model.User, error) {
-	updateUser, err := s.repo.UpdateUser(id, user.Surname, user.Name, user.Patronymic, user.Address, user.PassportNumber)
+func (s *UserTaskService) UpdateUser(ctx context.Context, id, version int64, patch model.UserPatch) (*model.User, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := validators.CheckUserPatch(patch); err != nil {
+		return nil, err
+	}
+
+	updateUser, err := s.repo.UpdateUser(auth.Actor(ctx), id, version, patch)
 	if err != nil {
-		if err != sql.ErrNoRows {
-			return nil, fmt.Errorf("%v", repository.ErrUserNotFound)
+		if errors.Is(err, repository.ErrUserNotFound) || errors.Is(err, repository.ErrUserExists) ||
+			errors.Is(err, repository.ErrVersionMismatch) {
+			return nil, err
 		}
 
 		slog.Error("can't update user", slog.String("err", err.Error()))
@@ -142,3 +179,62 @@ func (s *UserTaskService) UpdateUser(id int64, user model.UserUpdateRequest) (*m
 
 	return updateUser, nil
 }
+
+func (s *UserTaskService) RestoreUser(ctx context.Context, id int64) (*model.User, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.RestoreUser(auth.Actor(ctx), id)
+	if err != nil {
+		if !errors.Is(err, repository.ErrUserNotFound) && !errors.Is(err, repository.ErrUserExists) {
+			slog.Error("can't restore user", slog.String("err", err.Error()))
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// PurgeDeletedUsers deletes or anonymizes persons soft deleted longer than the retention period ago.
+func (s *UserTaskService) PurgeDeletedUsers(_ context.Context) error {
+	retention := config.GetPersonRetention()
+	if retention == 0 {
+		return nil
+	}
+
+	n, err := s.repo.PurgeDeletedUsers(time.Now().Add(-retention), config.GetPurgeMode())
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		slog.Info("deleted users purged", slog.Int64("count", n), slog.String("mode", config.GetPurgeMode()))
+	}
+
+	return nil
+}
+
+func (s *UserTaskService) GetUserHistory(ctx context.Context, filter model.HistoryFilter) (*model.HistoryPage, error) {
+	// history stays with the merged id, only access is checked against the survivor
+	if _, err := s.requireReport(ctx, filter.PersonID); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CheckPersonExists(filter.PersonID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	filter.Limit = config.GetPageSize(filter.Limit)
+
+	history, err := s.repo.GetUserHistory(filter)
+	if err != nil {
+		slog.Error("can't get user history", slog.String("err", err.Error()))
+		return nil, err
+	}
+
+	return history, nil
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"effective_mobile_testing/internal/auth"
+	"effective_mobile_testing/internal/model"
+	"effective_mobile_testing/internal/service/repository"
+	"errors"
+	"log/slog"
+	"strconv"
+)
+
+// ExportUser returns all data stored about the person. The export is recorded in the person history.
+func (s *UserTaskService) ExportUser(ctx context.Context, id int64) (*model.PersonalDataExport, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	actor := auth.Actor(ctx)
+
+	export, err := s.repo.ExportPersonalData(actor, id)
+	if err != nil {
+		if !errors.Is(err, repository.ErrUserNotFound) {
+			slog.Error("can't export personal data", slog.String("err", err.Error()))
+		}
+		return nil, err
+	}
+
+	if err := s.auditAccess(ctx, model.AccessUserExport, map[string]string{"id": strconv.FormatInt(id, 10)}, []int64{export.User.ID}); err != nil {
+		return nil, err
+	}
+
+	slog.Info("personal data exported", slog.Int64("user_id", id), slog.String("actor", actor))
+
+	return export, nil
+}
+
+// EraseUser anonymizes the person for an erasure request, tracked time stays in the reports.
+func (s *UserTaskService) EraseUser(ctx context.Context, id int64) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	actor := auth.Actor(ctx)
+
+	// read before the erasure removes them, to drop the registry answers cached for them
+	passports, err := s.repo.GetPersonPassports(id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.ErasePersonalData(actor, id); err != nil {
+		if !errors.Is(err, repository.ErrUserNotFound) {
+			slog.Error("can't erase personal data", slog.String("err", err.Error()))
+		}
+		return err
+	}
+
+	for _, p := range passports {
+		if err := s.registry.Forget(p); err != nil {
+			slog.Error("can't drop erased person from registry cache", slog.Int64("user_id", id), slog.String("err", err.Error()))
+			return err
+		}
+	}
+
+	slog.Info("personal data erased", slog.Int64("user_id", id), slog.String("actor", actor))
+
+	return nil
+}
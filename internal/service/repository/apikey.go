@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"database/sql"
+	"effective_mobile_testing/internal/auth"
+	"effective_mobile_testing/internal/model"
+	"effective_mobile_testing/internal/validators"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+const (
+	createAPIKeyQuery = `insert into api_key (name, prefix, key_hash, roles, person_id) values ($1, $2, $3, $4, nullif($5, 0))
+						returning id, name, prefix, roles, person_id, created_at`
+	listAPIKeysQuery  = `select id, name, prefix, roles, person_id, created_at, last_used_at, revoked_at from api_key order by id`
+	revokeAPIKeyQuery = `update api_key set revoked_at = now() where id = $1 and revoked_at is null`
+	useAPIKeyQuery    = `update api_key set last_used_at = now() where key_hash = $1 and revoked_at is null
+												returning id, name, prefix, roles, person_id, created_at, last_used_at`
+)
+
+func (repo *UserTaskRepo) CreateAPIKey(name, prefix, hash string, roles []string, personID int64) (*model.APIKey, error) {
+	var k model.APIKey
+
+	if err := repo.DB.QueryRowx(createAPIKeyQuery, name, prefix, hash, pq.Array(roles), personID).Scan(
+		&k.ID,
+		&k.Name,
+		&k.Prefix,
+		pq.Array(&k.Roles),
+		&k.PersonID,
+		&k.CreatedAt,
+	); err != nil {
+		if _, ok := validators.IsConstrainError(err); ok {
+			return nil, fmt.Errorf("%w: person %d not found", auth.ErrInvalidKey, personID)
+		}
+		return nil, err
+	}
+
+	return &k, nil
+}
+
+func (repo *UserTaskRepo) ListAPIKeys() ([]model.APIKey, error) {
+	rows, err := repo.DB.Query(listAPIKeysQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	keys := []model.APIKey{}
+
+	for rows.Next() {
+		var k model.APIKey
+		if err := rows.Scan(&k.ID, &k.Name, &k.Prefix, pq.Array(&k.Roles), &k.PersonID, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt); err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, k)
+	}
+
+	return keys, rows.Err()
+}
+
+func (repo *UserTaskRepo) RevokeAPIKey(id int64) error {
+	res, err := repo.DB.Exec(revokeAPIKeyQuery, id)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return auth.ErrKeyNotFound
+	}
+
+	return nil
+}
+
+// GetAPIKeyByHash finds an active key and records its use.
+func (repo *UserTaskRepo) GetAPIKeyByHash(hash string) (*model.APIKey, error) {
+	var k model.APIKey
+
+	if err := repo.DB.QueryRowx(useAPIKeyQuery, hash).Scan(
+		&k.ID,
+		&k.Name,
+		&k.Prefix,
+		pq.Array(&k.Roles),
+		&k.PersonID,
+		&k.CreatedAt,
+		&k.LastUsedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, auth.ErrKeyNotFound
+		}
+		return nil, err
+	}
+
+	return &k, nil
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"effective_mobile_testing/internal/model"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+const (
+	insertAccessAuditQuery      = `insert into access_audit (actor, action, filter, person_ids) values ($1, $2, $3, $4)`
+	listAccessAuditQueryPrefix  = `select id, actor, action, filter, person_ids, created_at from access_audit where 1=1 `
+	countAccessAuditQueryPrefix = `select count(*) from access_audit where 1=1 `
+
+	accessAuditSort = "-id"
+)
+
+// AddAccessAudit records a read of personal data. The table is append-only.
+func (repo *UserTaskRepo) AddAccessAudit(actor, action string, filter map[string]string, personIDs []int64) error {
+	b, err := json.Marshal(filter)
+	if err != nil {
+		return err
+	}
+
+	if personIDs == nil {
+		personIDs = []int64{}
+	}
+
+	_, err = repo.DB.Exec(insertAccessAuditQuery, actor, action, b, pq.Array(personIDs))
+
+	return err
+}
+
+func (repo *UserTaskRepo) ListAccessAudit(filter model.AccessAuditFilter) (*model.AccessAuditPage, error) {
+	where := ""
+	var args []interface{}
+	paramIndex := 1
+
+	if filter.Actor != "" {
+		where += fmt.Sprintf(" and actor = $%d", paramIndex)
+		args = append(args, filter.Actor)
+		paramIndex++
+	}
+
+	if filter.Action != "" {
+		where += fmt.Sprintf(" and action = $%d", paramIndex)
+		args = append(args, filter.Action)
+		paramIndex++
+	}
+
+	if filter.PersonID != 0 {
+		where += fmt.Sprintf(" and person_ids @> array[$%d::bigint]", paramIndex)
+		args = append(args, filter.PersonID)
+		paramIndex++
+	}
+
+	if filter.From != nil {
+		where += fmt.Sprintf(" and created_at >= $%d", paramIndex)
+		args = append(args, *filter.From)
+		paramIndex++
+	}
+
+	if filter.To != nil {
+		where += fmt.Sprintf(" and created_at < $%d", paramIndex)
+		args = append(args, *filter.To)
+		paramIndex++
+	}
+
+	var page model.AccessAuditPage
+
+	if filter.WithTotal {
+		var total int64
+		if err := repo.DB.QueryRowx(countAccessAuditQueryPrefix+where, args...).Scan(&total); err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
+
+	terms := []sortTerm{{field: "id", expr: "id", desc: true}}
+
+	filterKey := filterHash(where, args)
+
+	if filter.Cursor != "" {
+		c, err := decodeCursor(filter.Cursor, accessAuditSort, filterKey, len(terms))
+		if err != nil {
+			return nil, err
+		}
+
+		cond, keysetArgs, err := keyset(terms, c.Values, paramIndex)
+		if err != nil {
+			return nil, err
+		}
+
+		where += " and " + cond
+		args = append(args, keysetArgs...)
+		paramIndex += len(keysetArgs)
+	}
+
+	query := listAccessAuditQueryPrefix + where + orderBy(terms) + fmt.Sprintf(" limit $%d", paramIndex)
+	args = append(args, filter.Limit+1)
+
+	rows, err := repo.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	entries := []model.AccessAuditEntry{}
+
+	for rows.Next() {
+		var (
+			e   model.AccessAuditEntry
+			raw []byte
+		)
+
+		if err := rows.Scan(&e.ID, &e.Actor, &e.Action, &raw, pq.Array(&e.PersonIDs), &e.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(raw, &e.Filter); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+		last := entries[len(entries)-1]
+		page.NextCursor = encodeCursor(cursor{Sort: accessAuditSort, Filter: filterKey, Values: []string{strconv.FormatInt(last.ID, 10)}})
+	}
+
+	page.Entries = entries
+
+	return &page, nil
+}
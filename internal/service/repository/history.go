@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"effective_mobile_testing/internal/model"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	insertHistoryQuery      = `insert into person_history (person_id, action, actor, changes) values ($1, $2, $3, $4)`
+	listHistoryQueryPrefix  = `select id, person_id, action, actor, changes, changed_at from person_history where person_id = $1`
+	countHistoryQueryPrefix = `select count(*) from person_history where person_id = $1`
+
+	historySort = "-id"
+)
+
+// addHistory records a change of a person inside the caller's transaction.
+func addHistory(tx *sqlx.Tx, personID int64, action, actor string, changes map[string]model.FieldChange) error {
+	b, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(insertHistoryQuery, personID, action, actor, b)
+
+	return err
+}
+
+// diffUsers returns the person fields that differ between before and after.
+func diffUsers(before, after model.User) map[string]model.FieldChange {
+	changes := make(map[string]model.FieldChange)
+
+	for _, f := range []struct {
+		name     string
+		old, new string
+	}{
+		{"surname", before.Surname, after.Surname},
+		{"name", before.Name, after.Name},
+		{"patronymic", before.Patronymic, after.Patronymic},
+		{"address", before.Address, after.Address},
+		{"passport_number", before.PassportNumber, after.PassportNumber},
+	} {
+		if f.old != f.new {
+			changes[f.name] = model.NewFieldChange(f.old, f.new)
+		}
+	}
+
+	// history is kept in plain jsonb, so passports are only recorded masked
+	if _, ok := changes["passport_number"]; ok {
+		changes["passport_number"] = model.NewFieldChange(maskPassport(before.PassportNumber), maskPassport(after.PassportNumber))
+	}
+
+	return changes
+}
+
+// maskPassport keeps the last two digits, the same as migration 0009 does for older history.
+func maskPassport(p string) string {
+	if len(p) < 2 {
+		return p
+	}
+
+	return "**** ****" + p[len(p)-2:]
+}
+
+// GetUserHistory returns changes of a person newest first, paginated by id.
+func (repo *UserTaskRepo) GetUserHistory(filter model.HistoryFilter) (*model.HistoryPage, error) {
+	where := ""
+	args := []interface{}{filter.PersonID}
+	paramIndex := 2
+
+	var page model.HistoryPage
+
+	if filter.WithTotal {
+		var total int64
+		if err := repo.DB.QueryRowx(countHistoryQueryPrefix, args...).Scan(&total); err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
+
+	terms := []sortTerm{{field: "id", expr: "id", desc: true}}
+
+	filterKey := filterHash(where, args)
+
+	if filter.Cursor != "" {
+		c, err := decodeCursor(filter.Cursor, historySort, filterKey, len(terms))
+		if err != nil {
+			return nil, err
+		}
+
+		cond, keysetArgs, err := keyset(terms, c.Values, paramIndex)
+		if err != nil {
+			return nil, err
+		}
+
+		where += " and " + cond
+		args = append(args, keysetArgs...)
+		paramIndex += len(keysetArgs)
+	}
+
+	query := listHistoryQueryPrefix + where + orderBy(terms) + fmt.Sprintf(" limit $%d", paramIndex)
+	args = append(args, filter.Limit+1)
+
+	rows, err := repo.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	history := []model.PersonChange{}
+
+	for rows.Next() {
+		var (
+			ch      model.PersonChange
+			changes []byte
+		)
+
+		if err := rows.Scan(&ch.ID, &ch.PersonID, &ch.Action, &ch.Actor, &changes, &ch.ChangedAt); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(changes, &ch.Changes); err != nil {
+			return nil, err
+		}
+
+		history = append(history, ch)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(history) > filter.Limit {
+		history = history[:filter.Limit]
+		last := history[len(history)-1]
+		page.NextCursor = encodeCursor(cursor{Sort: historySort, Filter: filterKey, Values: []string{strconv.FormatInt(last.ID, 10)}})
+	}
+
+	page.History = history
+
+	return &page, nil
+}
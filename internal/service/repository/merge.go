@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"database/sql"
+	"effective_mobile_testing/internal/model"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+const (
+	// candidates share a similar surname (trigram index) or the blind index of the passport number without the series
+	duplicateCandidatesQuery = `with target as (
+			select normalize_text(surname) as surname,
+				normalize_text(surname || ' ' || name || ' ' || coalesce(patronymic, '')) as full_name,
+				normalize_text(address) as address,
+				passport_number_hash as passport
+			from person where id = $1 and deleted_at is null
+		)
+		select p.id, p.surname, p.name, coalesce(p.patronymic, ''), p.address, p.passport_enc, p.version,
+			similarity(normalize_text(p.surname || ' ' || p.name || ' ' || coalesce(p.patronymic, '')), t.full_name),
+			similarity(normalize_text(p.address), t.address),
+			coalesce(p.passport_number_hash = t.passport, false)
+		from person p, target t
+		where p.id <> $1 and p.deleted_at is null
+			and (normalize_text(p.surname) % t.surname or p.passport_number_hash = t.passport)`
+	lockMergeQuery     = `select count(*) from (select id from person where id in ($1, $2) and deleted_at is null for update) p`
+	moveTasksQuery     = `update task set user_id = $1 where user_id = $2`
+	moveMembersQuery   = `update team_member set person_id = $1 where person_id = $2`
+	mergePersonQuery   = `update person set deleted_at = now(), merged_into = $1, version = version + 1 where id = $2`
+	moveRedirectsQuery = `update person set merged_into = $1 where merged_into = $2`
+	touchSurvivorQuery = `update person set version = version + 1 where id = $1
+							returning id, surname, name, coalesce(patronymic, ''), address, passport_enc, version`
+
+	// memberships of both persons in one team over overlapping periods, the same check as adding a member
+	overlappingMembersQuery = `select d.team_id from team_member d
+							join team_member s on s.team_id = d.team_id and s.person_id = $1
+							where d.person_id = $2 and daterange(s.valid_from, s.valid_to) && daterange(d.valid_from, d.valid_to)
+							limit 1`
+)
+
+// ErrSameUser is returned when a person is merged into itself.
+var ErrSameUser = errors.New("can't merge a user into itself")
+
+// FindDuplicateCandidates returns active persons that look like the person id, with raw similarity scores.
+// The caller decides which of them are worth showing.
+func (repo *UserTaskRepo) FindDuplicateCandidates(id int64) ([]model.DuplicateCandidate, error) {
+	if err := repo.CheckUserIDPerson(id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	rows, err := repo.DB.Query(duplicateCandidatesQuery, id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	candidates := []model.DuplicateCandidate{}
+
+	for rows.Next() {
+		var c model.DuplicateCandidate
+
+		if err := rows.Scan(
+			&c.User.ID,
+			&c.User.Surname,
+			&c.User.Name,
+			&c.User.Patronymic,
+			&c.User.Address,
+			repo.passport(&c.User.PassportNumber),
+			&c.User.Version,
+			&c.NameSimilarity,
+			&c.AddressSimilarity,
+			&c.SamePassport,
+		); err != nil {
+			return nil, err
+		}
+
+		candidates = append(candidates, c)
+	}
+
+	return candidates, rows.Err()
+}
+
+// MergeUsers moves the tasks and team memberships of the duplicate to the survivor and soft deletes the duplicate
+// with a redirect to the survivor. Persons merged into the duplicate earlier are redirected too,
+// so a redirect is never more than one hop. When both are members of a team over overlapping periods
+// the merge is refused with ErrMembershipOverlaps, one of the memberships has to be ended first.
+func (repo *UserTaskRepo) MergeUsers(actor string, survivorID, duplicateID int64) (*model.User, error) {
+	if survivorID == duplicateID {
+		return nil, ErrSameUser
+	}
+
+	tx := repo.DB.MustBegin()
+	defer tx.Rollback()
+
+	var locked int
+	if err := tx.QueryRowx(lockMergeQuery, survivorID, duplicateID).Scan(&locked); err != nil {
+		return nil, err
+	}
+	if locked != 2 {
+		return nil, ErrUserNotFound
+	}
+
+	var teamID int64
+	err := tx.QueryRowx(overlappingMembersQuery, survivorID, duplicateID).Scan(&teamID)
+	if err == nil {
+		return nil, fmt.Errorf("%w: both users are members of team %d", ErrMembershipOverlaps, teamID)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	res, err := tx.Exec(moveTasksQuery, survivorID, duplicateID)
+	if err != nil {
+		return nil, err
+	}
+
+	moved, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(moveMembersQuery, survivorID, duplicateID); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(mergePersonQuery, survivorID, duplicateID); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(moveRedirectsQuery, survivorID, duplicateID); err != nil {
+		return nil, err
+	}
+
+	var user model.User
+
+	if err := tx.QueryRowx(touchSurvivorQuery, survivorID).Scan(
+		&user.ID,
+		&user.Surname,
+		&user.Name,
+		&user.Patronymic,
+		&user.Address,
+		repo.passport(&user.PassportNumber),
+		&user.Version,
+	); err != nil {
+		return nil, err
+	}
+
+	survivor := strconv.FormatInt(survivorID, 10)
+	duplicate := strconv.FormatInt(duplicateID, 10)
+	tasks := strconv.FormatInt(moved, 10)
+
+	if err := addHistory(tx, duplicateID, model.HistoryMerged, actor, map[string]model.FieldChange{
+		"merged_into": {New: &survivor},
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := addHistory(tx, survivorID, model.HistoryMerged, actor, map[string]model.FieldChange{
+		"merged_from": {New: &duplicate},
+		"tasks":       {New: &tasks},
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := addEvent(tx, model.EventUserMerged, model.UserMergedEvent{
+		SurvivorID:  survivorID,
+		DuplicateID: duplicateID,
+		MovedTasks:  moved,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"effective_mobile_testing/internal/model"
+	"encoding/json"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	insertOutboxQuery = `insert into outbox (event, payload) values ($1, $2)`
+	lockOutboxQuery   = `select id, event, payload from outbox where published_at is null
+							order by id limit $1 for update skip locked`
+	markOutboxQuery  = `update outbox set published_at = $1 where id = $2`
+	purgeOutboxQuery = `delete from outbox where published_at < $1`
+)
+
+// addEvent writes the event to the outbox inside the caller's transaction,
+// so it is published only if the change itself commits.
+func addEvent(tx *sqlx.Tx, event string, data any) error {
+	payload, err := json.Marshal(model.Event{Event: event, OccurredAt: time.Now().UTC(), Data: data})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(insertOutboxQuery, event, payload)
+
+	return err
+}
+
+// PublishOutbox locks up to limit unpublished messages, passes them to publish in order
+// and marks the successful ones. It stops at the first failure so ordering is kept.
+func (repo *UserTaskRepo) PublishOutbox(limit int, publish func(model.OutboxMessage) error) (int, error) {
+	tx := repo.DB.MustBegin()
+	defer tx.Rollback()
+
+	rows, err := tx.Query(lockOutboxQuery, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	var messages []model.OutboxMessage
+
+	for rows.Next() {
+		var m model.OutboxMessage
+		if err := rows.Scan(&m.ID, &m.Event, &m.Payload); err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		messages = append(messages, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	published := 0
+	var publishErr error
+
+	for _, m := range messages {
+		if publishErr = publish(m); publishErr != nil {
+			break
+		}
+
+		if _, err := tx.Exec(markOutboxQuery, time.Now(), m.ID); err != nil {
+			return 0, err
+		}
+		published++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return published, publishErr
+}
+
+// PurgeOutbox deletes messages published before the given time. Unpublished ones are kept.
+func (repo *UserTaskRepo) PurgeOutbox(before time.Time) (int64, error) {
+	res, err := repo.DB.Exec(purgeOutboxQuery, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"effective_mobile_testing/internal/model"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidSort   = errors.New("invalid sort")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// personSortColumns whitelists sortable person columns. Nullable columns are
+// coalesced so keyset comparisons never meet NULL. Passports are encrypted and can't be sorted.
+var personSortColumns = map[string]string{
+	"id":         "id",
+	"surname":    "surname",
+	"name":       "name",
+	"patronymic": "coalesce(patronymic, '')",
+	"address":    "address",
+}
+
+type sortTerm struct {
+	field string
+	expr  string
+	desc  bool
+}
+
+// cursor points right after the last row of a page. Sort and a hash of the filter are kept
+// so a cursor can't be reused with a different order or filter.
+type cursor struct {
+	Sort   string   `json:"s"`
+	Filter string   `json:"f"`
+	Values []string `json:"v"`
+}
+
+// filterHash identifies the rows a list selects: its where clause and arguments before the keyset is added.
+func filterHash(where string, args []interface{}) string {
+	h := sha256.New()
+	fmt.Fprint(h, where, args)
+
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:12])
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s, sort, filter string, terms int) (cursor, error) {
+	var c cursor
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	if err := json.Unmarshal(b, &c); err != nil || c.Sort != sort || c.Filter != filter || len(c.Values) != terms {
+		return c, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// parseSort turns a sort parameter like "surname,-id" into terms, checked against columns.
+// id is always added last so the order is stable.
+func parseSort(sort string, columns map[string]string) ([]sortTerm, error) {
+	var (
+		terms []sortTerm
+		seen  = make(map[string]bool)
+	)
+
+	if sort != "" {
+		for _, field := range strings.Split(sort, ",") {
+			field = strings.TrimSpace(field)
+
+			desc := strings.HasPrefix(field, "-")
+			field = strings.TrimPrefix(field, "-")
+
+			expr, ok := columns[field]
+			if !ok || seen[field] {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidSort, field)
+			}
+			seen[field] = true
+
+			terms = append(terms, sortTerm{field: field, expr: expr, desc: desc})
+		}
+	}
+
+	if !seen["id"] {
+		terms = append(terms, sortTerm{field: "id", expr: "id"})
+	}
+
+	return terms, nil
+}
+
+func orderBy(terms []sortTerm) string {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		parts[i] = t.expr + " asc"
+		if t.desc {
+			parts[i] = t.expr + " desc"
+		}
+	}
+
+	return " order by " + strings.Join(parts, ", ")
+}
+
+// keyset returns a condition selecting rows after the cursor values in terms order:
+// (t1 > v1) or (t1 = v1 and t2 > v2) or ..., with < for descending terms.
+func keyset(terms []sortTerm, values []string, paramIndex int) (string, []interface{}, error) {
+	args := make([]interface{}, len(terms))
+
+	for i, t := range terms {
+		if t.field != "id" {
+			args[i] = values[i]
+			continue
+		}
+
+		id, err := strconv.ParseInt(values[i], 10, 64)
+		if err != nil {
+			return "", nil, ErrInvalidCursor
+		}
+		args[i] = id
+	}
+
+	var or []string
+
+	for i, t := range terms {
+		var and []string
+		for j := 0; j < i; j++ {
+			and = append(and, fmt.Sprintf("%s = $%d", terms[j].expr, paramIndex+j))
+		}
+
+		op := ">"
+		if t.desc {
+			op = "<"
+		}
+		and = append(and, fmt.Sprintf("%s %s $%d", t.expr, op, paramIndex+i))
+
+		or = append(or, "("+strings.Join(and, " and ")+")")
+	}
+
+	return "(" + strings.Join(or, " or ") + ")", args, nil
+}
+
+func personSortValue(u model.User, field string) string {
+	switch field {
+	case "surname":
+		return u.Surname
+	case "name":
+		return u.Name
+	case "patronymic":
+		return u.Patronymic
+	case "address":
+		return u.Address
+	default:
+		return strconv.FormatInt(u.ID, 10)
+	}
+}
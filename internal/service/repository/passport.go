@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"database/sql"
+	"effective_mobile_testing/internal/secure"
+	"fmt"
+	"strings"
+)
+
+const (
+	selectPassportsToSealQuery = `select id, passport_number, passport_enc from person
+							where passport_number is not null or (passport_enc is not null and split_part(passport_enc, ':', 1) <> $1)
+							order by id limit $2 for update skip locked`
+	sealPassportQuery = `update person set passport_number = null, passport_enc = $1, passport_hash = $2, passport_number_hash = $3 where id = $4`
+
+	sealBatchSize = 100
+)
+
+// sealedPassport is what is stored for a passport: the ciphertext, the blind index of the
+// whole passport and the blind index of the number without the series. Nil for no passport.
+type sealedPassport struct {
+	enc        *string
+	hash       *string
+	numberHash *string
+}
+
+// normalizePassport joins series and number with a single space so the blind index doesn't depend on spacing.
+func normalizePassport(p string) string {
+	return strings.Join(strings.Fields(p), " ")
+}
+
+func (repo *UserTaskRepo) passportHash(p string) string {
+	return repo.Passports.BlindIndex(normalizePassport(p))
+}
+
+// passportNumberHash indexes the number without the series, for duplicate search.
+func (repo *UserTaskRepo) passportNumberHash(p string) *string {
+	fields := strings.Fields(p)
+	if len(fields) != 2 {
+		return nil
+	}
+
+	h := repo.Passports.BlindIndex("number:" + fields[1])
+
+	return &h
+}
+
+func (repo *UserTaskRepo) sealPassport(p string) (sealedPassport, error) {
+	p = normalizePassport(p)
+	if p == "" {
+		return sealedPassport{}, nil
+	}
+
+	enc, err := repo.Passports.Encrypt(p)
+	if err != nil {
+		return sealedPassport{}, err
+	}
+
+	hash := repo.passportHash(p)
+
+	return sealedPassport{enc: &enc, hash: &hash, numberHash: repo.passportNumberHash(p)}, nil
+}
+
+// passport returns a scanner that decrypts a passport_enc column into dst.
+func (repo *UserTaskRepo) passport(dst *string) sql.Scanner {
+	return passportScanner{cipher: repo.Passports, dst: dst}
+}
+
+type passportScanner struct {
+	cipher *secure.Cipher
+	dst    *string
+}
+
+func (s passportScanner) Scan(src interface{}) error {
+	var enc string
+
+	switch v := src.(type) {
+	case nil:
+		*s.dst = ""
+		return nil
+	case []byte:
+		enc = string(v)
+	case string:
+		enc = v
+	default:
+		return fmt.Errorf("unexpected passport type %T", src)
+	}
+
+	p, err := s.cipher.Decrypt(enc)
+	if err != nil {
+		return err
+	}
+	*s.dst = p
+
+	return nil
+}
+
+// SealPassports encrypts plain text passport numbers left from before encryption and re-encrypts
+// passports whose key is not the active one. Run it after adding a key to rotate, then the old key can go.
+func (repo *UserTaskRepo) SealPassports() (int64, error) {
+	var total int64
+
+	for {
+		n, err := repo.sealPassportBatch()
+		if err != nil {
+			return total, err
+		}
+		if n == 0 {
+			return total, nil
+		}
+
+		total += n
+	}
+}
+
+func (repo *UserTaskRepo) sealPassportBatch() (int64, error) {
+	tx := repo.DB.MustBegin()
+	defer tx.Rollback()
+
+	rows, err := tx.Query(selectPassportsToSealQuery, repo.Passports.ActiveKey(), sealBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	plain := make(map[int64]string)
+
+	for rows.Next() {
+		var (
+			id       int64
+			passport sql.NullString
+			enc      sql.NullString
+		)
+
+		if err := rows.Scan(&id, &passport, &enc); err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		if passport.Valid {
+			plain[id] = passport.String
+			continue
+		}
+
+		p, err := repo.Passports.Decrypt(enc.String)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("person %d: %w", id, err)
+		}
+		plain[id] = p
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for id, p := range plain {
+		sealed, err := repo.sealPassport(p)
+		if err != nil {
+			return 0, err
+		}
+
+		if _, err := tx.Exec(sealPassportQuery, sealed.enc, sealed.hash, sealed.numberHash, id); err != nil {
+			return 0, err
+		}
+	}
+
+	return int64(len(plain)), tx.Commit()
+}
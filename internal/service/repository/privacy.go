@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"database/sql"
+	"effective_mobile_testing/internal/model"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	exportPersonQuery  = `select id, surname, name, coalesce(patronymic, ''), address, passport_enc, deleted_at, version from person where id = $1`
+	exportTasksQuery   = `select id, name, start_tracking, stop_tracking from task where user_id = $1 order by id`
+	exportHistoryOrder = ` order by id`
+	// the person and everyone merged into it are the same human
+	erasePersonQuery = `update person set surname = '', name = '', patronymic = null, address = '', passport_number = null,
+							passport_enc = null, passport_hash = null, passport_number_hash = null,
+							anonymized_at = coalesce(anonymized_at, now()), deleted_at = coalesce(deleted_at, now()), version = version + 1
+							where id = $1 or merged_into = $1 returning id`
+	eraseHistoryQuery = `update person_history set changes = (
+							select coalesce(jsonb_object_agg(key, case when key = any($2) then '{"old": null, "new": null}'::jsonb else value end), '{}'::jsonb)
+							from jsonb_each(changes))
+							where person_id = any($1)`
+	personPassportsQuery = `select passport_enc from person where (id = $1 or merged_into = $1) and passport_enc is not null`
+)
+
+// personalFields are erased from history, the rest of a change (who and when) is kept.
+var personalFields = []string{"surname", "name", "patronymic", "address", "passport_number"}
+
+// ExportPersonalData returns the person, deleted or not, with all tasks and history,
+// and records the export in the history.
+func (repo *UserTaskRepo) ExportPersonalData(actor string, id int64) (*model.PersonalDataExport, error) {
+	tx := repo.DB.MustBegin()
+	defer tx.Rollback()
+
+	export := model.PersonalDataExport{
+		ExportedAt: time.Now().UTC(),
+		Tasks:      []model.ExportedTask{},
+		History:    []model.PersonChange{},
+	}
+
+	if err := tx.QueryRowx(exportPersonQuery, id).Scan(
+		&export.User.ID,
+		&export.User.Surname,
+		&export.User.Name,
+		&export.User.Patronymic,
+		&export.User.Address,
+		repo.passport(&export.User.PassportNumber),
+		&export.User.DeletedAt,
+		&export.User.Version,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	rows, err := tx.Query(exportTasksQuery, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		var t model.ExportedTask
+		if err := rows.Scan(&t.ID, &t.Name, &t.StartTracking, &t.StopTracking); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		export.Tasks = append(export.Tasks, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.Query(listHistoryQueryPrefix+exportHistoryOrder, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		var (
+			ch      model.PersonChange
+			changes []byte
+		)
+
+		if err := rows.Scan(&ch.ID, &ch.PersonID, &ch.Action, &ch.Actor, &changes, &ch.ChangedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		if err := json.Unmarshal(changes, &ch.Changes); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		export.History = append(export.History, ch)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := addHistory(tx, id, model.HistoryExported, actor, map[string]model.FieldChange{}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &export, nil
+}
+
+// GetPersonPassports returns the passports of the person and the persons merged into it, deleted or not.
+func (repo *UserTaskRepo) GetPersonPassports(id int64) ([]string, error) {
+	rows, err := repo.DB.Query(personPassportsQuery, id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var passports []string
+
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(repo.passport(&p)); err != nil {
+			return nil, err
+		}
+
+		passports = append(passports, p)
+	}
+
+	return passports, rows.Err()
+}
+
+// ErasePersonalData anonymizes the person and the persons merged into it, removes their personal
+// fields from the history, the shared registry cache and the sync report. Event payloads carry only ids.
+// Tasks are kept for reports. Erasing an already erased person only repeats the cleanup.
+func (repo *UserTaskRepo) ErasePersonalData(actor string, id int64) error {
+	tx := repo.DB.MustBegin()
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(eraseRegistryCacheQuery, id); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(eraseSyncReportQuery, id); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(erasePersonQuery, id)
+	if err != nil {
+		return err
+	}
+
+	var ids []int64
+
+	for rows.Next() {
+		var erased int64
+		if err := rows.Scan(&erased); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, erased)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(ids) == 0 {
+		return ErrUserNotFound
+	}
+
+	if _, err := tx.Exec(eraseHistoryQuery, pq.Array(ids), pq.Array(personalFields)); err != nil {
+		return err
+	}
+
+	if err := addHistory(tx, id, model.HistoryErased, actor, map[string]model.FieldChange{}); err != nil {
+		return err
+	}
+
+	if err := addEvent(tx, model.EventUserErased, model.UserDeletedEvent{ID: id}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"database/sql"
+	"effective_mobile_testing/internal/model"
+	"encoding/json"
+	"errors"
+)
+
+const (
+	// data is the person as JSON encrypted with the passport keys
+	getRegistryCacheQuery = `select data, expires_at from registry_cache where passport_hash = $1 and expires_at > now()`
+	setRegistryCacheQuery = `insert into registry_cache (passport_hash, data, expires_at) values ($1, $2, $3)
+							on conflict (passport_hash) do update set data = excluded.data, expires_at = excluded.expires_at`
+	purgeRegistryCacheQuery  = `delete from registry_cache where expires_at <= now()`
+	deleteRegistryCacheQuery = `delete from registry_cache where passport_hash = $1`
+	// run before the person's passport hash is erased
+	eraseRegistryCacheQuery = `delete from registry_cache where passport_hash in (
+							select passport_hash from person where (id = $1 or merged_into = $1) and passport_hash is not null)`
+)
+
+// GetRegistryCache returns the cached registry answer for the passport, nil when there is none.
+func (repo *UserTaskRepo) GetRegistryCache(passport string) (*model.RegistryCacheEntry, error) {
+	var (
+		entry model.RegistryCacheEntry
+		enc   *string
+	)
+
+	if err := repo.DB.QueryRowx(getRegistryCacheQuery, repo.passportHash(passport)).Scan(&enc, &entry.ExpiresAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if enc != nil {
+		data, err := repo.Passports.Decrypt(*enc)
+		if err != nil {
+			return nil, err
+		}
+
+		entry.User = &model.UserFromAPI{}
+		if err := json.Unmarshal([]byte(data), entry.User); err != nil {
+			return nil, err
+		}
+	}
+
+	return &entry, nil
+}
+
+func (repo *UserTaskRepo) SetRegistryCache(passport string, entry model.RegistryCacheEntry) error {
+	var enc *string
+
+	if entry.User != nil {
+		data, err := json.Marshal(entry.User)
+		if err != nil {
+			return err
+		}
+
+		sealed, err := repo.Passports.Encrypt(string(data))
+		if err != nil {
+			return err
+		}
+		enc = &sealed
+	}
+
+	_, err := repo.DB.Exec(setRegistryCacheQuery, repo.passportHash(passport), enc, entry.ExpiresAt)
+
+	return err
+}
+
+func (repo *UserTaskRepo) DeleteRegistryCache(passport string) error {
+	_, err := repo.DB.Exec(deleteRegistryCacheQuery, repo.passportHash(passport))
+
+	return err
+}
+
+func (repo *UserTaskRepo) PurgeRegistryCache() (int64, error) {
+	res, err := repo.DB.Exec(purgeRegistryCacheQuery)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -4,77 +4,141 @@ import (
 	"database/sql"
 	"effective_mobile_testing/internal/db"
 	"effective_mobile_testing/internal/model"
+	"effective_mobile_testing/internal/secure"
 	"effective_mobile_testing/internal/validators"
 	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
 const (
-	createUserQuery = `insert into person (surname, name, patronymic, address, passport_number)
-						values ($1, $2, $3, $4, $5) returning surname, name,patronymic,address`
-	startTaskQuery         = `insert into task (name, start_tracking, user_id) values ($1, $2, $3)`
+	createUserQuery = `insert into person (surname, name, patronymic, address, passport_enc, passport_hash, passport_number_hash)
+						values ($1, $2, $3, $4, $5, $6, $7) returning id, surname, name,patronymic,address, version`
+	startTaskQuery         = `insert into task (name, start_tracking, user_id) select $1, $2, id from person where id = $3 and deleted_at is null`
 	checkUserIDTaskQuery   = `select user_id from task where user_id = $1`
-	checkUserIDPersonQuery = `select id from person where id = $1`
-	stopTaskQuery          = `update task set stop_tracking=$1 where user_id=$2 and name=$3`
-	getLaborCosts          = `select name, floor(EXTRACT(EPOCH from (stop_tracking - start_tracking)) / 60) as duration from task 
-						where user_id=$1 and stop_tracking is not null  order by duration desc `
-	deleteFromTaskQuery   = `delete from task where user_id = $1`
-	deleteFromPersonQuery = `delete from person where id = $1`
-	selectForUpdateQuery  = `select surname, name, patronymic, address, passport_number from person where id =$1 for update`
-	updatePersonQuery     = `update person set surname=$1, name=$2, address=$3, patronymic=$4, passport_number=$5 where id = $6
-								returning id, surname, name, patronymic, address, passport_number`
+	checkUserIDPersonQuery = `select id from person where id = $1 and deleted_at is null`
+	personExistsQuery      = `select id from person where id = $1` // deleted too, their history and reports stay readable
+	stopTaskQuery          = `update task set stop_tracking=$1 where user_id=$2 and name=$3 and stop_tracking is null`
+	laborDurationExpr      = `floor(EXTRACT(EPOCH from (stop_tracking - start_tracking)) / 60)`
+	laborCostsQueryPrefix  = `select id, name, ` + laborDurationExpr + ` as duration from task`
+	countLaborCostsPrefix  = `select count(*) from task`
+	laborCostsSort         = "-duration,-id"
+	softDeletePersonQuery  = `update person set deleted_at = now(), version = version + 1 where id = $1 and deleted_at is null
+							and ($2::bigint = 0 or version = $2) returning deleted_at`
+	personVersionQuery = `select version from person where id = $1 and deleted_at is null`
+	// a merged id resolves to the person it was merged into
+	getPersonQuery = `select id, surname, name, coalesce(patronymic, ''), address, passport_enc, version
+							from person where id = (select coalesce(merged_into, id) from person where id = $1) and deleted_at is null`
+	resolvePersonQuery = `select coalesce(merged_into, id) from person where id = $1`
+	restorePersonQuery = `update person set deleted_at = null, version = version + 1 where id = $1 and deleted_at is not null and anonymized_at is null and merged_into is null
+							returning id, surname, name, coalesce(patronymic, ''), address, passport_enc, version`
+	// merged duplicates are kept, their ids resolve to the survivor
+	purgeTasksQuery       = `delete from task where user_id in (select id from person where deleted_at < $1 and merged_into is null)`
+	purgePersonsQuery     = `delete from person where deleted_at < $1 and merged_into is null`
+	anonymizePersonsQuery = `update person set surname = '', name = '', patronymic = null, address = '', passport_number = null,
+							passport_enc = null, passport_hash = null, passport_number_hash = null,
+							anonymized_at = now(), version = version + 1 where deleted_at < $1 and anonymized_at is null`
+	selectPersonQuery = `select surname, name, coalesce(patronymic, ''), address, passport_enc, version from person where id =$1 and deleted_at is null`
+	updatePersonQuery = `update person set %s, version = version + 1 where id = $%d and version = $%d and deleted_at is null
+								returning id, surname, name, coalesce(patronymic, ''), address, passport_enc, version`
 )
 
 var (
 	ErrUserExists   = errors.New("user already exists")
 	ErrUserNotFound = errors.New("user not found")
+	// ErrVersionMismatch means the person was changed since the caller read it.
+	ErrVersionMismatch = errors.New("user version mismatch")
 )
 
 type UserTaskRepo struct {
-	DB db.DB
+	DB        db.DB
+	Passports *secure.Cipher
 }
 
-func NewUserTaskRepo(db db.DB) *UserTaskRepo {
-	return &UserTaskRepo{DB: db}
+func NewUserTaskRepo(db db.DB, passports *secure.Cipher) *UserTaskRepo {
+	return &UserTaskRepo{DB: db, Passports: passports}
 }
 
-func (repo *UserTaskRepo) CreateUser(surname, name, patronymic, address, passportNumber string) (*model.User, error) {
+func (repo *UserTaskRepo) CreateUser(actor, surname, name, patronymic, address, passportNumber string) (*model.User, error) {
 	var user model.User
 
-	if err := repo.DB.QueryRowx(createUserQuery, surname, name, patronymic, address, passportNumber).Scan(
+	passport, err := repo.sealPassport(passportNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := repo.DB.MustBegin()
+	defer tx.Rollback()
+
+	if err := tx.QueryRowx(createUserQuery, surname, name, patronymic, address, passport.enc, passport.hash, passport.numberHash).Scan(
+		&user.ID,
 		&user.Surname,
 		&user.Name,
 		&user.Patronymic,
 		&user.Address,
+		&user.Version,
 	); err != nil {
 		err, ok := validators.IsConstrainError(err)
 		if ok {
-			return nil, fmt.Errorf("%v:%w", ErrUserExists, err)
+			return nil, fmt.Errorf("%w:%w", ErrUserExists, err)
 		}
 
 		return nil, err
 	}
 
+	user.PassportNumber = normalizePassport(passportNumber)
+
+	if err := addHistory(tx, user.ID, model.HistoryCreated, actor, diffUsers(model.User{}, user)); err != nil {
+		return nil, err
+	}
+
+	if err := addEvent(tx, model.EventUserCreated, model.UserEvent{ID: user.ID, Version: user.Version}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
 	return &user, nil
 }
 
 func (repo *UserTaskRepo) StartTask(userId int64, taskName string) error {
 	startTime := time.Now()
 
-	_, err := repo.DB.Exec(startTaskQuery, taskName, startTime, userId)
+	tx := repo.DB.MustBegin()
+	defer tx.Rollback()
+
+	res, err := tx.Exec(startTaskQuery, taskName, startTime, userId)
 	if err != nil {
 		err, ok := validators.IsConstrainError(err)
 		if ok {
-			return fmt.Errorf("%v:%w", ErrUserNotFound, err)
+			return fmt.Errorf("%w:%w", ErrUserNotFound, err)
 		}
 
 		return err
 	}
 
-	return nil
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrUserNotFound
+	}
+
+	if err := addEvent(tx, model.EventTrackingStarted, model.TrackingEvent{
+		UserID:   userId,
+		TaskName: taskName,
+		At:       startTime,
+	}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
+// StopTask stops the running tasks with the name. Stopped tasks keep their time, and the event
+// is published only when a task was actually stopped.
 func (repo *UserTaskRepo) StopTask(userId int64, taskName string) error {
 	stopTime := time.Now()
 
@@ -84,41 +148,134 @@ func (repo *UserTaskRepo) StopTask(userId int64, taskName string) error {
 		}
 	}
 
-	_, err := repo.DB.Exec(stopTaskQuery, stopTime, userId, taskName)
+	tx := repo.DB.MustBegin()
+	defer tx.Rollback()
+
+	res, err := tx.Exec(stopTaskQuery, stopTime, userId, taskName)
 	if err != nil {
 		return err
 	}
 
-	return nil
-}
+	if n, err := res.RowsAffected(); err == nil && n > 0 {
+		if err := addEvent(tx, model.EventTrackingStopped, model.TrackingEvent{
+			UserID:   userId,
+			TaskName: taskName,
+			At:       stopTime,
+		}); err != nil {
+			return err
+		}
+	}
 
-func (repo *UserTaskRepo) GetLaborCosts(userId int64) ([]model.ResponseLobarCost, error) {
+	return tx.Commit()
+}
 
-	if err := repo.CheckUserIDPerson(userId); err != nil {
+// GetLaborCosts returns the tracked time of the person, soft deleted persons included,
+// longest first and paginated by duration and task id.
+func (repo *UserTaskRepo) GetLaborCosts(filter model.LaborCostFilter) (*model.LaborCostPage, error) {
+	if err := repo.CheckPersonExists(filter.UserID); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("%v", ErrUserNotFound)
+			return nil, ErrUserNotFound
 		}
+		return nil, err
 	}
 
-	var lobarCosts []model.ResponseLobarCost
+	where := " where user_id = $1 and stop_tracking is not null"
+	args := []interface{}{filter.UserID}
+	paramIndex := 2
+
+	var page model.LaborCostPage
+
+	if filter.WithTotal {
+		var total int64
+		if err := repo.DB.QueryRowx(countLaborCostsPrefix+where, args...).Scan(&total); err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
 
-	rows, err := repo.DB.Query(getLaborCosts, userId)
+	terms := []sortTerm{{field: "duration", expr: laborDurationExpr, desc: true}, {field: "id", expr: "id", desc: true}}
+
+	filterKey := filterHash(where, args)
+
+	if filter.Cursor != "" {
+		c, err := decodeCursor(filter.Cursor, laborCostsSort, filterKey, len(terms))
+		if err != nil {
+			return nil, err
+		}
+
+		cond, keysetArgs, err := keyset(terms, c.Values, paramIndex)
+		if err != nil {
+			return nil, err
+		}
+
+		where += " and " + cond
+		args = append(args, keysetArgs...)
+		paramIndex += len(keysetArgs)
+	}
+
+	query := laborCostsQueryPrefix + where + orderBy(terms) + fmt.Sprintf(" limit $%d", paramIndex)
+	args = append(args, filter.Limit+1)
+
+	rows, err := repo.DB.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
 
 	defer rows.Close()
 
+	lobarCosts := []model.ResponseLobarCost{}
+	var ids []int64
+
 	for rows.Next() {
-		var lobarCost model.ResponseLobarCost
-		if err := rows.Scan(&lobarCost.TaskName, &lobarCost.DurationMinutes); err != nil {
+		var (
+			id        int64
+			lobarCost model.ResponseLobarCost
+		)
+		if err := rows.Scan(&id, &lobarCost.TaskName, &lobarCost.DurationMinutes); err != nil {
 			return nil, err
 		}
 
 		lobarCosts = append(lobarCosts, lobarCost)
+		ids = append(ids, id)
 	}
 
-	return lobarCosts, nil
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(lobarCosts) > filter.Limit {
+		lobarCosts = lobarCosts[:filter.Limit]
+		last := filter.Limit - 1
+		page.NextCursor = encodeCursor(cursor{Sort: laborCostsSort, Filter: filterKey, Values: []string{
+			strconv.Itoa(lobarCosts[last].DurationMinutes),
+			strconv.FormatInt(ids[last], 10),
+		}})
+	}
+
+	page.Costs = lobarCosts
+
+	return &page, nil
+}
+
+func (repo *UserTaskRepo) GetUser(id int64) (*model.User, error) {
+	var user model.User
+
+	if err := repo.DB.QueryRowx(getPersonQuery, id).Scan(
+		&user.ID,
+		&user.Surname,
+		&user.Name,
+		&user.Patronymic,
+		&user.Address,
+		repo.passport(&user.PassportNumber),
+		&user.Version,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return &user, nil
 }
 
 func (repo *UserTaskRepo) CheckUserIDPerson(userID int64) error {
@@ -131,6 +288,27 @@ func (repo *UserTaskRepo) CheckUserIDPerson(userID int64) error {
 	return nil
 }
 
+// CheckPersonExists is CheckUserIDPerson that also finds soft deleted persons.
+func (repo *UserTaskRepo) CheckPersonExists(id int64) error {
+	var personID int64
+
+	return repo.DB.QueryRowx(personExistsQuery, id).Scan(&personID)
+}
+
+// ResolvePersonID returns the person a merged id was merged into, other ids as they are. Soft deleted persons are found too.
+func (repo *UserTaskRepo) ResolvePersonID(id int64) (int64, error) {
+	var resolved int64
+
+	if err := repo.DB.QueryRowx(resolvePersonQuery, id).Scan(&resolved); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrUserNotFound
+		}
+		return 0, err
+	}
+
+	return resolved, nil
+}
+
 func (repo *UserTaskRepo) CheckUserIDTask(id int64) error {
 	var userId int
 	err := repo.DB.QueryRowx(checkUserIDTaskQuery, id).Scan(&userId)
@@ -141,56 +319,105 @@ func (repo *UserTaskRepo) CheckUserIDTask(id int64) error {
 	return nil
 }
 
-func (repo *UserTaskRepo) GetUserByFilters(limit, offset int, id int64, surname, name, patronymic, address, passportNumber string) (*[]model.User, error) {
-	query := "SELECT id, surname, name, patronymic, address, passport_number FROM person WHERE 1=1 "
+func (repo *UserTaskRepo) GetUserByFilters(filter model.UserFilter) (*model.UserPage, error) {
+	where := " WHERE 1=1 "
 	var args []interface{}
 	paramIndex := 1
 
-	if id != 0 {
-		query += fmt.Sprintf(" and id = $%d", paramIndex)
-		args = append(args, id)
-		paramIndex++
+	if !filter.IncludeDeleted {
+		where += " and deleted_at is null"
 	}
 
-	if surname != "" {
-		query += fmt.Sprintf(" and surname = $%d", paramIndex)
-		args = append(args, surname)
+	if filter.ID != 0 {
+		where += fmt.Sprintf(" and id = $%d", paramIndex)
+		args = append(args, filter.ID)
 		paramIndex++
 	}
 
-	if name != "" {
-		query += fmt.Sprintf(" and name = $%d", paramIndex)
-		args = append(args, name)
+	if filter.ManagerID != 0 {
+		where += " and " + fmt.Sprintf(managedPersonsCondition, paramIndex)
+		args = append(args, filter.ManagerID)
 		paramIndex++
 	}
 
-	if patronymic != "" {
-		query += fmt.Sprintf(" and patronymic = $%d", paramIndex)
-		args = append(args, patronymic)
+	for _, f := range []struct {
+		column string
+		filter model.FieldFilter
+	}{
+		{"surname", filter.Surname},
+		{"name", filter.Name},
+		{"patronymic", filter.Patronymic},
+		{"address", filter.Address},
+	} {
+		if f.filter.Value == "" {
+			continue
+		}
+
+		where += " and " + matchCondition(f.column, f.filter.Mode, paramIndex)
+		args = append(args, matchArg(f.filter.Mode, f.filter.Value))
 		paramIndex++
 	}
 
-	if address != "" {
-		query += fmt.Sprintf(" and address = $%d", paramIndex)
-		args = append(args, address)
+	if filter.PassportNumber != "" {
+		where += fmt.Sprintf(" and passport_hash = $%d", paramIndex)
+		args = append(args, repo.passportHash(filter.PassportNumber))
 		paramIndex++
 	}
 
-	if passportNumber != "" {
-		query += fmt.Sprintf(" and passport_number = $%d", paramIndex)
-		args = append(args, address)
+	for _, word := range strings.Fields(filter.Query) {
+		where += fmt.Sprintf(` and (%s or %s or %s or %s)`,
+			matchCondition("surname", model.MatchContains, paramIndex),
+			matchCondition("name", model.MatchContains, paramIndex),
+			matchCondition("patronymic", model.MatchContains, paramIndex),
+			matchCondition("address", model.MatchContains, paramIndex),
+		)
+		args = append(args, matchArg(model.MatchContains, word))
 		paramIndex++
 	}
 
-	if limit != 0 {
-		query += fmt.Sprintf(" limit $%d", paramIndex)
-		args = append(args, limit)
-		paramIndex++
+	var page model.UserPage
+
+	if filter.WithTotal {
+		var total int64
+		if err := repo.DB.QueryRowx("SELECT count(*) FROM person"+where, args...).Scan(&total); err != nil {
+			return nil, err
+		}
+		page.Total = &total
 	}
 
-	if offset != 0 {
+	terms, err := parseSort(filter.Sort, personSortColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	filterKey := filterHash(where, args)
+
+	if filter.Cursor != "" {
+		c, err := decodeCursor(filter.Cursor, filter.Sort, filterKey, len(terms))
+		if err != nil {
+			return nil, err
+		}
+
+		cond, keysetArgs, err := keyset(terms, c.Values, paramIndex)
+		if err != nil {
+			return nil, err
+		}
+
+		where += " and " + cond
+		args = append(args, keysetArgs...)
+		paramIndex += len(keysetArgs)
+	}
+
+	query := "SELECT id, surname, name, coalesce(patronymic, ''), address, passport_enc, deleted_at, version FROM person" + where + orderBy(terms)
+
+	// one extra row tells whether there is a next page
+	query += fmt.Sprintf(" limit $%d", paramIndex)
+	args = append(args, filter.Limit+1)
+	paramIndex++
+
+	if filter.Offset != 0 {
 		query += fmt.Sprintf(" offset $%d", paramIndex)
-		args = append(args, offset-1)
+		args = append(args, filter.Offset)
 	}
 
 	rows, err := repo.DB.Query(query, args...)
@@ -200,65 +427,215 @@ func (repo *UserTaskRepo) GetUserByFilters(limit, offset int, id int64, surname,
 
 	defer rows.Close()
 
-	var users []model.User
+	users := []model.User{}
 
 	for rows.Next() {
 		var user model.User
 
-		if err := rows.Scan(&user.ID, &user.Surname, &user.Name, &user.Patronymic, &user.Address, &user.PassportNumber); err != nil {
+		if err := rows.Scan(&user.ID, &user.Surname, &user.Name, &user.Patronymic, &user.Address, repo.passport(&user.PassportNumber), &user.DeletedAt, &user.Version); err != nil {
 			return nil, err
 		}
 
 		users = append(users, user)
 	}
 
-	return &users, nil
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(users) > filter.Limit {
+		users = users[:filter.Limit]
+
+		last := users[len(users)-1]
+		values := make([]string, len(terms))
+		for i, t := range terms {
+			values[i] = personSortValue(last, t.field)
+		}
+
+		page.NextCursor = encodeCursor(cursor{Sort: filter.Sort, Filter: filterKey, Values: values})
+	}
+
+	page.Users = users
+
+	return &page, nil
 }
 
-func (repo *UserTaskRepo) DeleteUser(id int64) error {
-	if _, err := repo.DB.Exec(deleteFromTaskQuery, id); err != nil {
+// DeleteUser soft deletes the person if it is still at version. Version 0 skips the check.
+func (repo *UserTaskRepo) DeleteUser(actor string, id, version int64) error {
+	tx := repo.DB.MustBegin()
+	defer tx.Rollback()
+
+	var deletedAt time.Time
+
+	if err := tx.QueryRowx(softDeletePersonQuery, id, version).Scan(&deletedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repo.versionError(id)
+		}
+		return err
+	}
+
+	deleted := deletedAt.Format(time.RFC3339)
+	if err := addHistory(tx, id, model.HistoryDeleted, actor, map[string]model.FieldChange{
+		"deleted_at": {New: &deleted},
+	}); err != nil {
 		return err
 	}
 
-	if _, err := repo.DB.Exec(deleteFromPersonQuery, id); err != nil {
+	if err := addEvent(tx, model.EventUserDeleted, model.UserDeletedEvent{ID: id}); err != nil {
 		return err
 	}
 
-	return nil
+	return tx.Commit()
 }
 
-func (repo *UserTaskRepo) UpdateUser(id int64, surname, name, patronymic, address, passportNumber string) (*model.User, error) {
-	if err := repo.CheckUserIDPerson(id); err != nil {
+// UpdateUser applies only the fields set in the patch if the person is still at version.
+// Version 0 skips the check. A patch without fields returns the user unchanged.
+func (repo *UserTaskRepo) UpdateUser(actor string, id, version int64, patch model.UserPatch) (*model.User, error) {
+	tx := repo.DB.MustBegin()
+	defer tx.Rollback()
+
+	old := model.User{ID: id}
+
+	// no row lock: the update below only matches the version read here
+	if err := tx.QueryRowx(selectPersonQuery, id).Scan(
+		&old.Surname,
+		&old.Name,
+		&old.Patronymic,
+		&old.Address,
+		repo.passport(&old.PassportNumber),
+		&old.Version,
+	); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("%v", ErrUserNotFound)
+			return nil, ErrUserNotFound
 		}
+		return nil, err
 	}
 
-	tx := repo.DB.MustBegin()
-	defer tx.Rollback()
+	if version != 0 && version != old.Version {
+		return nil, ErrVersionMismatch
+	}
 
-	if _, err := tx.Exec(selectForUpdateQuery, id); err != nil {
+	var (
+		set  []string
+		args []interface{}
+	)
+
+	for _, f := range []struct {
+		column string
+		field  model.PatchField
+	}{
+		{"surname", patch.Surname},
+		{"name", patch.Name},
+		{"patronymic", patch.Patronymic},
+		{"address", patch.Address},
+	} {
+		if !f.field.Set {
+			continue
+		}
+
+		args = append(args, f.field.Value)
+		set = append(set, fmt.Sprintf("%s = $%d", f.column, len(args)))
+	}
+
+	if patch.PassportNumber.Set {
+		var p string
+		if patch.PassportNumber.Value != nil {
+			p = *patch.PassportNumber.Value
+		}
+
+		passport, err := repo.sealPassport(p)
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, passport.enc, passport.hash, passport.numberHash)
+		set = append(set, fmt.Sprintf("passport_enc = $%d, passport_hash = $%d, passport_number_hash = $%d", len(args)-2, len(args)-1, len(args)))
+	}
+
+	if len(set) == 0 {
+		return &old, nil
+	}
+
+	args = append(args, id, old.Version)
+
+	var user model.User
+
+	if err := tx.QueryRowx(fmt.Sprintf(updatePersonQuery, strings.Join(set, ", "), len(args)-1, len(args)), args...).Scan(
+		&user.ID,
+		&user.Surname,
+		&user.Name,
+		&user.Patronymic,
+		&user.Address,
+		repo.passport(&user.PassportNumber),
+		&user.Version,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrVersionMismatch
+		}
+
+		err, ok := validators.IsConstrainError(err)
+		if ok {
+			return nil, fmt.Errorf("%w:%w", ErrUserExists, err)
+		}
+
+		return nil, err
+	}
+
+	changes := diffUsers(old, user)
+
+	if err := addHistory(tx, id, model.HistoryUpdated, actor, changes); err != nil {
+		return nil, err
+	}
+
+	fields := make([]string, 0, len(changes))
+	for field := range changes {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	if err := addEvent(tx, model.EventUserUpdated, model.UserEvent{ID: user.ID, Version: user.Version, Fields: fields}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
 
+	return &user, nil
+}
+
+func (repo *UserTaskRepo) RestoreUser(actor string, id int64) (*model.User, error) {
+	tx := repo.DB.MustBegin()
+	defer tx.Rollback()
+
 	var user model.User
 
-	if err := tx.QueryRowx(
-		updatePersonQuery,
-		surname,
-		name,
-		patronymic,
-		address,
-		passportNumber,
-		id,
-	).Scan(
+	if err := tx.QueryRowx(restorePersonQuery, id).Scan(
 		&user.ID,
 		&user.Surname,
 		&user.Name,
 		&user.Patronymic,
 		&user.Address,
-		&user.PassportNumber,
+		repo.passport(&user.PassportNumber),
+		&user.Version,
 	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+
+		err, ok := validators.IsConstrainError(err)
+		if ok {
+			return nil, fmt.Errorf("%w:%w", ErrUserExists, err)
+		}
+
+		return nil, err
+	}
+
+	if err := addHistory(tx, id, model.HistoryRestored, actor, map[string]model.FieldChange{}); err != nil {
+		return nil, err
+	}
+
+	if err := addEvent(tx, model.EventUserRestored, model.UserEvent{ID: user.ID, Version: user.Version}); err != nil {
 		return nil, err
 	}
 
@@ -268,3 +645,47 @@ func (repo *UserTaskRepo) UpdateUser(id int64, surname, name, patronymic, addres
 
 	return &user, nil
 }
+
+// PurgeDeletedUsers removes persons soft deleted before the cutoff. With model.PurgeAnonymize
+// the rows and their tasks stay but personal data is wiped, so labor reports keep their totals.
+func (repo *UserTaskRepo) PurgeDeletedUsers(before time.Time, mode string) (int64, error) {
+	tx := repo.DB.MustBegin()
+	defer tx.Rollback()
+
+	var (
+		res sql.Result
+		err error
+	)
+
+	if mode == model.PurgeDelete {
+		if _, err := tx.Exec(purgeTasksQuery, before); err != nil {
+			return 0, err
+		}
+		res, err = tx.Exec(purgePersonsQuery, before)
+	} else {
+		res, err = tx.Exec(anonymizePersonsQuery, before)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return n, tx.Commit()
+}
+
+// versionError tells apart a missing person from a stale version after a conditional write matched no rows.
+func (repo *UserTaskRepo) versionError(id int64) error {
+	var version int64
+	if err := repo.DB.QueryRowx(personVersionQuery, id).Scan(&version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	return ErrVersionMismatch
+}
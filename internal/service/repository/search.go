@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"effective_mobile_testing/internal/model"
+	"fmt"
+	"strings"
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// matchCondition compares normalize_text(column) with parameter n so the pg_trgm
+// expression indexes from migration 0004 are used for every mode.
+func matchCondition(column, mode string, n int) string {
+	col := fmt.Sprintf("normalize_text(%s)", column)
+
+	switch mode {
+	case model.MatchPrefix, model.MatchContains:
+		return fmt.Sprintf("%s like $%d", col, n)
+	case model.MatchFuzzy:
+		return fmt.Sprintf("%s %% normalize_text($%d)", col, n)
+	default:
+		return fmt.Sprintf("%s = normalize_text($%d)", col, n)
+	}
+}
+
+// matchArg prepares the value for matchCondition. Like patterns are normalized
+// and escaped here, before the wildcards are added.
+func matchArg(mode, value string) string {
+	switch mode {
+	case model.MatchPrefix:
+		return likeEscaper.Replace(normalize(value)) + "%"
+	case model.MatchContains:
+		return "%" + likeEscaper.Replace(normalize(value)) + "%"
+	default:
+		return value
+	}
+}
+
+// normalize mirrors the normalize_text SQL function.
+func normalize(s string) string {
+	return strings.ReplaceAll(strings.ToLower(s), "ё", "е")
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"database/sql"
+	"effective_mobile_testing/internal/model"
+	"errors"
+	"time"
+)
+
+const (
+	runningTaskQuery = `select name, start_tracking, floor(extract(epoch from ($2 - start_tracking)) / 60) from task
+						where user_id = $1 and start_tracking is not null and stop_tracking is null
+						order by start_tracking desc limit 1`
+	// minutes tracked since each period start ($2, $3, $4), running tasks count up to now ($5)
+	trackedMinutesQuery = `select
+		coalesce(floor(sum(greatest(extract(epoch from least(coalesce(stop_tracking, $5), $5) - greatest(start_tracking, $2)), 0)) / 60), 0),
+		coalesce(floor(sum(greatest(extract(epoch from least(coalesce(stop_tracking, $5), $5) - greatest(start_tracking, $3)), 0)) / 60), 0),
+		coalesce(floor(sum(greatest(extract(epoch from least(coalesce(stop_tracking, $5), $5) - greatest(start_tracking, $4)), 0)) / 60), 0)
+		from task where user_id = $1 and start_tracking is not null and start_tracking < $5 and coalesce(stop_tracking, $5) > least($2, $3, $4)`
+	recentTasksQuery = `select name, start_tracking, stop_tracking, floor(extract(epoch from (coalesce(stop_tracking, $2) - start_tracking)) / 60)
+						from task where user_id = $1 and start_tracking is not null
+						order by start_tracking desc limit $3`
+)
+
+// GetTrackingSummary returns the running task, minutes tracked since dayStart, weekStart and monthStart
+// and the last recent tasks of the user. Durations are counted by the database like in GetLaborCosts.
+func (repo *UserTaskRepo) GetTrackingSummary(userID int64, now, dayStart, weekStart, monthStart time.Time, recent int) (*model.TrackingSummary, error) {
+	summary := model.TrackingSummary{RecentTasks: []model.TaskSummary{}}
+
+	var running model.RunningTask
+
+	err := repo.DB.QueryRowx(runningTaskQuery, userID, now).Scan(&running.TaskName, &running.StartTracking, &running.DurationMinutes)
+	switch {
+	case err == nil:
+		summary.Running = &running
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, err
+	}
+
+	if err := repo.DB.QueryRowx(trackedMinutesQuery, userID, dayStart, weekStart, monthStart, now).Scan(
+		&summary.TodayMinutes,
+		&summary.WeekMinutes,
+		&summary.MonthMinutes,
+	); err != nil {
+		return nil, err
+	}
+
+	rows, err := repo.DB.Query(recentTasksQuery, userID, now, recent)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var task model.TaskSummary
+		if err := rows.Scan(&task.TaskName, &task.StartTracking, &task.StopTracking, &task.DurationMinutes); err != nil {
+			return nil, err
+		}
+
+		summary.RecentTasks = append(summary.RecentTasks, task)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &summary, nil
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"database/sql"
+	"effective_mobile_testing/internal/model"
+	"effective_mobile_testing/internal/secure"
+	"encoding/json"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+const (
+	deleteSyncReportsQuery = `delete from registry_sync_report`
+	insertSyncReportQuery  = `insert into registry_sync_report (report, person_ids, finished_at) values ($1, $2, $3)`
+	getSyncReportQuery     = `select report from registry_sync_report order by id desc limit 1`
+	// a report naming an erased person is dropped whole, the next sync writes a new one
+	eraseSyncReportQuery = `delete from registry_sync_report
+							where person_ids && array(select id from person where id = $1 or merged_into = $1)`
+)
+
+// SaveSyncReport replaces the stored sync report. It is encrypted with the passport keys,
+// it carries the persons with their passports.
+func (repo *UserTaskRepo) SaveSyncReport(report *model.SyncReport) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	enc, err := repo.Passports.Encrypt(string(data))
+	if err != nil {
+		return err
+	}
+
+	var ids []int64
+	for _, r := range report.Changed {
+		ids = append(ids, r.UserID)
+	}
+	for _, f := range report.Failed {
+		ids = append(ids, f.UserID)
+	}
+
+	tx := repo.DB.MustBegin()
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(deleteSyncReportsQuery); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(insertSyncReportQuery, enc, pq.Array(ids), report.FinishedAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetSyncReport returns the stored sync report, nil when there is none. A report encrypted
+// with a key that was removed since is treated as missing.
+func (repo *UserTaskRepo) GetSyncReport() (*model.SyncReport, error) {
+	var enc string
+
+	if err := repo.DB.QueryRowx(getSyncReportQuery).Scan(&enc); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	data, err := repo.Passports.Decrypt(enc)
+	if err != nil {
+		if errors.Is(err, secure.ErrUnknownKey) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var report model.SyncReport
+	if err := json.Unmarshal([]byte(data), &report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
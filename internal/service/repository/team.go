@@ -0,0 +1,266 @@
+package repository
+
+import (
+	"database/sql"
+	"effective_mobile_testing/internal/model"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	createTeamQuery = `insert into team (name, kind, parent_id) values ($1, $2, $3)
+						returning id, name, kind, parent_id, created_at`
+	listTeamsQuery  = `select id, name, kind, parent_id, created_at from team order by name`
+	getTeamQuery    = `select id, name, kind, parent_id, created_at from team where id = $1`
+	deleteTeamQuery = `delete from team where id = $1`
+	// a membership may not overlap another one of the same person in the same team
+	addTeamMemberQuery = `insert into team_member (team_id, person_id, role, valid_from, valid_to)
+						select $1, $2, $3, $4, $5
+						where not exists (select 1 from team_member where team_id = $1 and person_id = $2
+							and daterange(valid_from, valid_to) && daterange($4::date, $5::date))
+						returning id`
+	teamMembersQuery = `select m.id, m.team_id, m.person_id, p.surname, p.name, coalesce(p.patronymic, ''), m.role, m.valid_from, m.valid_to
+						from team_member m join person p on p.id = m.person_id
+						where m.team_id = $1 order by m.valid_from, m.id`
+	// memberships starting on or after the end date are dropped, the ones still open are closed at it
+	dropTeamMemberQuery = `delete from team_member where team_id = $1 and person_id = $2 and valid_from >= $3`
+	endTeamMemberQuery  = `update team_member set valid_to = $3 where team_id = $1 and person_id = $2
+						and valid_from < $3 and (valid_to is null or valid_to > $3)`
+
+	// teams managed by $1 on date $2, with every team below them
+	managedTeams = `with recursive managed as (
+			select team_id as id from team_member where person_id = $1 and role = 'manager'
+				and valid_from <= $2::date and (valid_to is null or valid_to > $2::date)
+			union
+			select t.id from team t join managed on t.parent_id = managed.id
+		)`
+	managesTeamQuery   = managedTeams + ` select exists (select 1 from managed where id = $3)`
+	managesPersonQuery = managedTeams + ` select exists (select 1 from managed join team_member m on m.team_id = managed.id
+			where m.person_id = $3 and m.valid_from <= $2::date and (m.valid_to is null or m.valid_to > $2::date))`
+
+	// the person $%[1]d and the current members of the teams they manage, for listings of managers
+	managedPersonsCondition = `(id = $%[1]d or id in (with recursive managed as (
+			select team_id as id from team_member where person_id = $%[1]d and role = 'manager'
+				and valid_from <= current_date and (valid_to is null or valid_to > current_date)
+			union
+			select t.id from team t join managed on t.parent_id = managed.id
+		) select m.person_id from managed join team_member m on m.team_id = managed.id
+			where m.valid_from <= current_date and (m.valid_to is null or m.valid_to > current_date)))`
+
+	// time tracked in [$2, $3) by members of the team $1 and the teams below it, only while they were members.
+	// Periods of one person in several teams are merged first, so overlapping memberships are counted once.
+	// range_agg and multiranges need PostgreSQL 14.
+	teamReportQuery = `with recursive tree as (
+			select id from team where id = $1
+			union
+			select t.id from team t join tree on t.parent_id = tree.id
+		), membership as (
+			select m.person_id, range_agg(tsrange(m.valid_from, m.valid_to) * tsrange($2, $3)) as periods
+			from team_member m join tree on tree.id = m.team_id
+			where tsrange(m.valid_from, m.valid_to) && tsrange($2, $3)
+			group by m.person_id
+		), tracked as (
+			select ms.person_id, t.id as task_id,
+				(select coalesce(sum(extract(epoch from upper(r) - lower(r))), 0)
+					from unnest(ms.periods * multirange(tsrange(t.start_tracking, coalesce(t.stop_tracking, $4)))) r) as seconds
+			from membership ms join task t on t.user_id = ms.person_id
+			where t.start_tracking is not null and coalesce(t.stop_tracking, $4) >= t.start_tracking
+				and ms.periods && tsrange(t.start_tracking, coalesce(t.stop_tracking, $4))
+		)
+		select p.id, p.surname, p.name, coalesce(p.patronymic, ''), count(tr.task_id), coalesce(floor(sum(tr.seconds) / 60), 0)::bigint
+		from membership ms
+		join person p on p.id = ms.person_id
+		left join tracked tr on tr.person_id = ms.person_id
+		group by p.id, p.surname, p.name, p.patronymic
+		order by 6 desc, p.id`
+)
+
+var (
+	ErrTeamNotFound       = errors.New("team not found")
+	ErrTeamExists         = errors.New("team already exists")
+	ErrMemberNotFound     = errors.New("team member not found")
+	ErrMembershipOverlaps = errors.New("membership overlaps an existing one")
+)
+
+func (repo *UserTaskRepo) CreateTeam(name, kind string, parentID *int64) (*model.Team, error) {
+	if parentID != nil {
+		if _, err := repo.GetTeam(*parentID); err != nil {
+			return nil, err
+		}
+	}
+
+	var t model.Team
+
+	if err := repo.DB.QueryRowx(createTeamQuery, name, kind, parentID).Scan(&t.ID, &t.Name, &t.Kind, &t.ParentID, &t.CreatedAt); err != nil {
+		var errPq *pq.Error
+		if errors.As(err, &errPq) && errPq.Code == "23505" {
+			return nil, ErrTeamExists
+		}
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+func (repo *UserTaskRepo) ListTeams() ([]model.Team, error) {
+	rows, err := repo.DB.Query(listTeamsQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	teams := []model.Team{}
+
+	for rows.Next() {
+		var t model.Team
+		if err := rows.Scan(&t.ID, &t.Name, &t.Kind, &t.ParentID, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		teams = append(teams, t)
+	}
+
+	return teams, rows.Err()
+}
+
+func (repo *UserTaskRepo) GetTeam(id int64) (*model.Team, error) {
+	var t model.Team
+
+	if err := repo.DB.QueryRowx(getTeamQuery, id).Scan(&t.ID, &t.Name, &t.Kind, &t.ParentID, &t.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTeamNotFound
+		}
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// DeleteTeam removes the team with its memberships, teams below it move to the top level.
+func (repo *UserTaskRepo) DeleteTeam(id int64) error {
+	res, err := repo.DB.Exec(deleteTeamQuery, id)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrTeamNotFound
+	}
+
+	return nil
+}
+
+func (repo *UserTaskRepo) GetTeamMembers(teamID int64) ([]model.TeamMember, error) {
+	rows, err := repo.DB.Query(teamMembersQuery, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	members := []model.TeamMember{}
+
+	for rows.Next() {
+		var m model.TeamMember
+		if err := rows.Scan(&m.ID, &m.TeamID, &m.PersonID, &m.Surname, &m.Name, &m.Patronymic, &m.Role, &m.ValidFrom, &m.ValidTo); err != nil {
+			return nil, err
+		}
+
+		members = append(members, m)
+	}
+
+	return members, rows.Err()
+}
+
+func (repo *UserTaskRepo) AddTeamMember(teamID, personID int64, role string, validFrom time.Time, validTo *time.Time) error {
+	if _, err := repo.GetTeam(teamID); err != nil {
+		return err
+	}
+
+	if err := repo.CheckUserIDPerson(personID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	var id int64
+
+	if err := repo.DB.QueryRowx(addTeamMemberQuery, teamID, personID, role, validFrom, validTo).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrMembershipOverlaps
+		}
+		return err
+	}
+
+	return nil
+}
+
+// EndTeamMember ends the memberships of the person in the team at the date, exclusive.
+func (repo *UserTaskRepo) EndTeamMember(teamID, personID int64, at time.Time) error {
+	tx := repo.DB.MustBegin()
+	defer tx.Rollback()
+
+	dropped, err := tx.Exec(dropTeamMemberQuery, teamID, personID, at)
+	if err != nil {
+		return err
+	}
+
+	ended, err := tx.Exec(endTeamMemberQuery, teamID, personID, at)
+	if err != nil {
+		return err
+	}
+
+	d, _ := dropped.RowsAffected()
+	e, _ := ended.RowsAffected()
+	if d+e == 0 {
+		return ErrMemberNotFound
+	}
+
+	return tx.Commit()
+}
+
+// ManagesTeam tells whether the person manages the team, directly or through a department, at the date.
+func (repo *UserTaskRepo) ManagesTeam(managerID, teamID int64, at time.Time) (bool, error) {
+	var ok bool
+
+	err := repo.DB.QueryRowx(managesTeamQuery, managerID, at, teamID).Scan(&ok)
+
+	return ok, err
+}
+
+// ManagesPerson tells whether the person is a member of a team managed by managerID at the date.
+func (repo *UserTaskRepo) ManagesPerson(managerID, personID int64, at time.Time) (bool, error) {
+	var ok bool
+
+	err := repo.DB.QueryRowx(managesPersonQuery, managerID, at, personID).Scan(&ok)
+
+	return ok, err
+}
+
+// GetTeamReport returns the minutes the members tracked in [from, to) while they were in the team.
+// Running tasks count up to now.
+func (repo *UserTaskRepo) GetTeamReport(teamID int64, from, to, now time.Time) ([]model.MemberLabor, error) {
+	rows, err := repo.DB.Query(teamReportQuery, teamID, from, to, now)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	members := []model.MemberLabor{}
+
+	for rows.Next() {
+		var m model.MemberLabor
+		if err := rows.Scan(&m.PersonID, &m.Surname, &m.Name, &m.Patronymic, &m.Tasks, &m.Minutes); err != nil {
+			return nil, err
+		}
+
+		members = append(members, m)
+	}
+
+	return members, rows.Err()
+}
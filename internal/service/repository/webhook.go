@@ -0,0 +1,333 @@
+package repository
+
+import (
+	"database/sql"
+	"effective_mobile_testing/internal/model"
+	"effective_mobile_testing/internal/webhook"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	createWebhookQuery = `insert into webhook (url, secret, events) values ($1, $2, $3)
+						returning id, url, secret, events, active, created_at`
+	listWebhooksQuery     = `select id, url, events, active, created_at from webhook order by id`
+	webhooksForEventQuery = `select id, url, secret, events, active, created_at from webhook where active and $1 = any(events)`
+	getWebhookQuery       = `select id, url, secret, events, active, created_at from webhook where id = $1`
+	deleteWebhookQuery    = `delete from webhook where id = $1`
+	createDeliveryQuery   = `insert into webhook_delivery (webhook_id, outbox_id, event, payload, status, next_attempt_at) values ($1, $2, $3, $4, $5, now())
+									on conflict (webhook_id, outbox_id) do nothing returning id, created_at, next_attempt_at`
+	// the claimed rows are moved forward by the lease, so no other poller picks them while they are sent
+	claimDeliveriesQuery = `update webhook_delivery d set next_attempt_at = now() + make_interval(secs => $2)
+									from webhook w
+									where w.id = d.webhook_id and d.id in (
+										select id from webhook_delivery where status = 'pending' and next_attempt_at <= now()
+										order by next_attempt_at limit $1 for update skip locked)
+									returning d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.response_code, d.last_error,
+										d.created_at, d.delivered_at, d.next_attempt_at, w.id, w.url, w.secret`
+	updateDeliveryQuery        = `update webhook_delivery set status=$1, attempts=$2, response_code=$3, last_error=$4, delivered_at=$5, next_attempt_at=$6 where id = $7`
+	getDeliveryQuery           = `select id, webhook_id, event, payload, status, attempts, response_code, last_error, created_at, delivered_at, next_attempt_at from webhook_delivery where id = $1`
+	listDeliveriesQueryPrefix  = `select id, webhook_id, event, payload, status, attempts, response_code, last_error, created_at, delivered_at, next_attempt_at from webhook_delivery where 1=1 `
+	countDeliveriesQueryPrefix = `select count(*) from webhook_delivery where 1=1 `
+
+	deliveriesSort = "-id"
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("delivery not found")
+)
+
+func (repo *UserTaskRepo) CreateWebhook(url, secret string, events []string) (*model.Webhook, error) {
+	var w model.Webhook
+
+	if err := repo.DB.QueryRowx(createWebhookQuery, url, secret, pq.Array(events)).Scan(
+		&w.ID,
+		&w.URL,
+		&w.Secret,
+		pq.Array(&w.Events),
+		&w.Active,
+		&w.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	return &w, nil
+}
+
+func (repo *UserTaskRepo) ListWebhooks() ([]model.Webhook, error) {
+	rows, err := repo.DB.Query(listWebhooksQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	webhooks := []model.Webhook{}
+
+	for rows.Next() {
+		var w model.Webhook
+		if err := rows.Scan(&w.ID, &w.URL, pq.Array(&w.Events), &w.Active, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		webhooks = append(webhooks, w)
+	}
+
+	return webhooks, rows.Err()
+}
+
+func (repo *UserTaskRepo) GetWebhooksForEvent(event string) ([]model.Webhook, error) {
+	rows, err := repo.DB.Query(webhooksForEventQuery, event)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var webhooks []model.Webhook
+
+	for rows.Next() {
+		var w model.Webhook
+		if err := rows.Scan(&w.ID, &w.URL, &w.Secret, pq.Array(&w.Events), &w.Active, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		webhooks = append(webhooks, w)
+	}
+
+	return webhooks, rows.Err()
+}
+
+func (repo *UserTaskRepo) GetWebhook(id int64) (*model.Webhook, error) {
+	var w model.Webhook
+
+	if err := repo.DB.QueryRowx(getWebhookQuery, id).Scan(
+		&w.ID,
+		&w.URL,
+		&w.Secret,
+		pq.Array(&w.Events),
+		&w.Active,
+		&w.CreatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+
+	return &w, nil
+}
+
+func (repo *UserTaskRepo) DeleteWebhook(id int64) error {
+	res, err := repo.DB.Exec(deleteWebhookQuery, id)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// CreateDelivery stores a pending delivery. outboxID is the message it is created for, nil for replays.
+// A second delivery of the same message to the webhook gives webhook.ErrDeliveryExists.
+func (repo *UserTaskRepo) CreateDelivery(webhookID int64, outboxID *int64, event string, payload []byte) (*model.WebhookDelivery, error) {
+	d := model.WebhookDelivery{
+		WebhookID: webhookID,
+		Event:     event,
+		Payload:   payload,
+		Status:    model.DeliveryPending,
+	}
+
+	if err := repo.DB.QueryRowx(createDeliveryQuery, webhookID, outboxID, event, payload, d.Status).Scan(&d.ID, &d.CreatedAt, &d.NextAttemptAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, webhook.ErrDeliveryExists
+		}
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+// ClaimDeliveries returns up to limit pending deliveries that are due and postpones them by lease.
+func (repo *UserTaskRepo) ClaimDeliveries(limit int, lease time.Duration) ([]webhook.Due, error) {
+	rows, err := repo.DB.Query(claimDeliveriesQuery, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var due []webhook.Due
+
+	for rows.Next() {
+		var (
+			dd      webhook.Due
+			payload []byte
+		)
+
+		if err := rows.Scan(
+			&dd.Delivery.ID,
+			&dd.Delivery.WebhookID,
+			&dd.Delivery.Event,
+			&payload,
+			&dd.Delivery.Status,
+			&dd.Delivery.Attempts,
+			&dd.Delivery.ResponseCode,
+			&dd.Delivery.LastError,
+			&dd.Delivery.CreatedAt,
+			&dd.Delivery.DeliveredAt,
+			&dd.Delivery.NextAttemptAt,
+			&dd.Webhook.ID,
+			&dd.Webhook.URL,
+			&dd.Webhook.Secret,
+		); err != nil {
+			return nil, err
+		}
+
+		dd.Delivery.Payload = payload
+		due = append(due, dd)
+	}
+
+	return due, rows.Err()
+}
+
+func (repo *UserTaskRepo) UpdateDelivery(d *model.WebhookDelivery) error {
+	_, err := repo.DB.Exec(updateDeliveryQuery, d.Status, d.Attempts, d.ResponseCode, d.LastError, d.DeliveredAt, d.NextAttemptAt, d.ID)
+
+	return err
+}
+
+func (repo *UserTaskRepo) GetDelivery(id int64) (*model.WebhookDelivery, error) {
+	d, err := scanDelivery(repo.DB.QueryRowx(getDeliveryQuery, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDeliveryNotFound
+		}
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// ListDeliveries returns deliveries newest first, paginated by id.
+func (repo *UserTaskRepo) ListDeliveries(filter model.DeliveryFilter) (*model.DeliveryPage, error) {
+	where := ""
+	var args []interface{}
+	paramIndex := 1
+
+	if filter.WebhookID != 0 {
+		where += fmt.Sprintf(" and webhook_id = $%d", paramIndex)
+		args = append(args, filter.WebhookID)
+		paramIndex++
+	}
+
+	if filter.Status != "" {
+		where += fmt.Sprintf(" and status = $%d", paramIndex)
+		args = append(args, filter.Status)
+		paramIndex++
+	}
+
+	var page model.DeliveryPage
+
+	if filter.WithTotal {
+		var total int64
+		if err := repo.DB.QueryRowx(countDeliveriesQueryPrefix+where, args...).Scan(&total); err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
+
+	terms := []sortTerm{{field: "id", expr: "id", desc: true}}
+
+	filterKey := filterHash(where, args)
+
+	if filter.Cursor != "" {
+		c, err := decodeCursor(filter.Cursor, deliveriesSort, filterKey, len(terms))
+		if err != nil {
+			return nil, err
+		}
+
+		cond, keysetArgs, err := keyset(terms, c.Values, paramIndex)
+		if err != nil {
+			return nil, err
+		}
+
+		where += " and " + cond
+		args = append(args, keysetArgs...)
+		paramIndex += len(keysetArgs)
+	}
+
+	query := listDeliveriesQueryPrefix + where + orderBy(terms) + fmt.Sprintf(" limit $%d", paramIndex)
+	args = append(args, filter.Limit+1)
+
+	rows, err := repo.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	deliveries := []model.WebhookDelivery{}
+
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, *d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(deliveries) > filter.Limit {
+		deliveries = deliveries[:filter.Limit]
+		last := deliveries[len(deliveries)-1]
+		page.NextCursor = encodeCursor(cursor{Sort: deliveriesSort, Filter: filterKey, Values: []string{strconv.FormatInt(last.ID, 10)}})
+	}
+
+	page.Deliveries = deliveries
+
+	return &page, nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanDelivery(row scanner) (*model.WebhookDelivery, error) {
+	var (
+		d       model.WebhookDelivery
+		payload []byte
+	)
+
+	if err := row.Scan(
+		&d.ID,
+		&d.WebhookID,
+		&d.Event,
+		&payload,
+		&d.Status,
+		&d.Attempts,
+		&d.ResponseCode,
+		&d.LastError,
+		&d.CreatedAt,
+		&d.DeliveredAt,
+		&d.NextAttemptAt,
+	); err != nil {
+		return nil, err
+	}
+
+	d.Payload = payload
+
+	return &d, nil
+}
@@ -1,23 +1,63 @@
 package service
 
-import "effective_mobile_testing/internal/model"
+import (
+	"context"
+
+	"effective_mobile_testing/internal/model"
+	"time"
+)
 
 type UserTaskService struct {
-	repo Repository
+	repo     Repository
+	registry Registry
+	jobs     *bulkJobs
+}
+
+func NewUserTaskService(repo Repository, registry Registry) *UserTaskService {
+	return &UserTaskService{repo: repo, registry: registry, jobs: newBulkJobs()}
 }
 
-func NewUserTaskService(repo Repository) *UserTaskService {
-	return &UserTaskService{repo: repo}
+// Registry looks up persons by passport in the external passport registry, through a cache.
+type Registry interface {
+	GetPerson(ctx context.Context, passportSerie, passportNumber string) (model.UserFromAPI, error)
+	Stats() model.RegistryCacheStats
+	Forget(passport string) error
 }
 
 type Repository interface {
-	CreateUser(surname, name, patronymic, address, passportNumber string) (*model.User, error)
+	CreateUser(actor, surname, name, patronymic, address, passportNumber string) (*model.User, error)
 	StartTask(userId int64, taskName string) error
 	StopTask(userId int64, taskName string) error
-	GetLaborCosts(userId int64) ([]model.ResponseLobarCost, error)
+	GetLaborCosts(filter model.LaborCostFilter) (*model.LaborCostPage, error)
+	GetUser(id int64) (*model.User, error)
+	GetTrackingSummary(userID int64, now, dayStart, weekStart, monthStart time.Time, recent int) (*model.TrackingSummary, error)
 	CheckUserIDPerson(userID int64) error
+	CheckPersonExists(id int64) error
+	ResolvePersonID(id int64) (int64, error)
 	CheckUserIDTask(id int64) error
-	GetUserByFilters(limit, offset int, id int64, surname, name, patronymic, address, passportNumber string) (*[]model.User, error)
-	DeleteUser(id int64) error
-	UpdateUser(id int64, surname, name, patronymic, address, passportNumber string) (*model.User, error)
+	GetUserByFilters(filter model.UserFilter) (*model.UserPage, error)
+	DeleteUser(actor string, id, version int64) error
+	UpdateUser(actor string, id, version int64, patch model.UserPatch) (*model.User, error)
+	RestoreUser(actor string, id int64) (*model.User, error)
+	GetUserHistory(filter model.HistoryFilter) (*model.HistoryPage, error)
+	PurgeDeletedUsers(before time.Time, mode string) (int64, error)
+	SaveSyncReport(report *model.SyncReport) error
+	GetSyncReport() (*model.SyncReport, error)
+	FindDuplicateCandidates(id int64) ([]model.DuplicateCandidate, error)
+	MergeUsers(actor string, survivorID, duplicateID int64) (*model.User, error)
+	ExportPersonalData(actor string, id int64) (*model.PersonalDataExport, error)
+	ErasePersonalData(actor string, id int64) error
+	GetPersonPassports(id int64) ([]string, error)
+	CreateTeam(name, kind string, parentID *int64) (*model.Team, error)
+	ListTeams() ([]model.Team, error)
+	GetTeam(id int64) (*model.Team, error)
+	DeleteTeam(id int64) error
+	GetTeamMembers(teamID int64) ([]model.TeamMember, error)
+	AddTeamMember(teamID, personID int64, role string, validFrom time.Time, validTo *time.Time) error
+	EndTeamMember(teamID, personID int64, at time.Time) error
+	ManagesTeam(managerID, teamID int64, at time.Time) (bool, error)
+	ManagesPerson(managerID, personID int64, at time.Time) (bool, error)
+	GetTeamReport(teamID int64, from, to, now time.Time) ([]model.MemberLabor, error)
+	AddAccessAudit(actor, action string, filter map[string]string, personIDs []int64) error
+	ListAccessAudit(filter model.AccessAuditFilter) (*model.AccessAuditPage, error)
 }
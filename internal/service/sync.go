@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"effective_mobile_testing/internal/auth"
+	"effective_mobile_testing/internal/config"
+	"effective_mobile_testing/internal/model"
+	"effective_mobile_testing/internal/service/repository"
+	"effective_mobile_testing/internal/validators"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+)
+
+// syncActor is recorded in the history for changes applied by the scheduled re-sync.
+const syncActor = "registry-sync"
+
+var (
+	ErrNoPassport   = errors.New("user has no passport number to look up")
+	ErrRegistry     = errors.New("registry request failed")
+	ErrNoSyncReport = errors.New("registry sync has not run yet")
+)
+
+// SyncUser compares the stored person with the passport registry. With apply the
+// differences are written as a regular update, so they get history and events.
+func (s *UserTaskService) SyncUser(ctx context.Context, id int64, apply bool) (*model.SyncResult, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUser(id)
+	if err != nil {
+		if !errors.Is(err, repository.ErrUserNotFound) {
+			slog.Error("can't get user", slog.String("err", err.Error()))
+		}
+		return nil, err
+	}
+
+	return s.syncUser(ctx, user, apply)
+}
+
+// GetSyncReport returns the report of the last scheduled re-sync.
+func (s *UserTaskService) GetSyncReport(ctx context.Context) (*model.SyncReport, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	report, err := s.repo.GetSyncReport()
+	if err != nil {
+		slog.Error("can't get sync report", slog.String("err", err.Error()))
+		return nil, err
+	}
+	if report == nil {
+		return nil, ErrNoSyncReport
+	}
+
+	return report, nil
+}
+
+// SyncAllUsers re-syncs every person page by page and stores the report for GetSyncReport.
+// Changes are only applied when REGISTRY_SYNC_APPLY is set.
+func (s *UserTaskService) SyncAllUsers(ctx context.Context) error {
+	ctx = auth.WithPrincipal(ctx, auth.Principal{Name: syncActor, Roles: []string{auth.RoleAdmin}})
+
+	report := &model.SyncReport{
+		StartedAt: time.Now(),
+		Apply:     config.GetRegistrySyncApply(),
+		Changed:   []model.SyncResult{},
+		Failed:    []model.SyncFailure{},
+	}
+
+	filter := model.UserFilter{Limit: config.GetPageSize(0)}
+
+	for {
+		page, err := s.repo.GetUserByFilters(filter)
+		if err != nil {
+			return err
+		}
+
+		for i := range page.Users {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			report.Checked++
+
+			res, err := s.syncUser(ctx, &page.Users[i], report.Apply)
+			if err != nil {
+				report.Failed = append(report.Failed, model.SyncFailure{UserID: page.Users[i].ID, Error: err.Error()})
+				continue
+			}
+
+			if len(res.Changes) > 0 {
+				report.Changed = append(report.Changed, *res)
+				slog.Info("registry data changed", slog.Int64("user_id", res.UserID), slog.String("fields", changedFields(res.Changes)))
+			}
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+		filter.Cursor = page.NextCursor
+	}
+
+	report.FinishedAt = time.Now()
+	if err := s.repo.SaveSyncReport(report); err != nil {
+		return err
+	}
+
+	slog.Info("registry sync finished",
+		slog.Int("checked", report.Checked),
+		slog.Int("changed", len(report.Changed)),
+		slog.Int("failed", len(report.Failed)),
+		slog.Bool("applied", report.Apply),
+	)
+
+	return nil
+}
+
+func (s *UserTaskService) syncUser(ctx context.Context, user *model.User, apply bool) (*model.SyncResult, error) {
+	passport := strings.Fields(user.PassportNumber)
+	if !validators.CheckLenPassport(passport) {
+		return nil, ErrNoPassport
+	}
+
+	fromAPI, err := s.GetUserData(ctx, passport[0], passport[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRegistry, err)
+	}
+
+	patch, changes := registryPatch(*user, fromAPI)
+
+	res := &model.SyncResult{UserID: user.ID, Changes: changes, User: user}
+
+	if !apply || len(changes) == 0 {
+		return res, nil
+	}
+
+	updated, err := s.UpdateUser(ctx, user.ID, user.Version, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	res.Applied = true
+	res.User = updated
+
+	return res, nil
+}
+
+// registryPatch builds the update that makes user match the registry. Empty registry
+// values are ignored, except patronymic, which people may not have.
+func registryPatch(user model.User, fromAPI model.UserFromAPI) (model.UserPatch, map[string]model.FieldChange) {
+	var patch model.UserPatch
+	changes := make(map[string]model.FieldChange)
+
+	for _, f := range []struct {
+		name      string
+		old, new  string
+		field     *model.PatchField
+		clearable bool
+	}{
+		{"surname", user.Surname, fromAPI.Surname, &patch.Surname, false},
+		{"name", user.Name, fromAPI.Name, &patch.Name, false},
+		{"patronymic", user.Patronymic, fromAPI.Patronymic, &patch.Patronymic, true},
+		{"address", user.Address, fromAPI.Address, &patch.Address, false},
+	} {
+		if f.old == f.new || (f.new == "" && !f.clearable) {
+			continue
+		}
+
+		change := model.NewFieldChange(f.old, f.new)
+
+		changes[f.name] = change
+		*f.field = model.PatchField{Set: true, Value: change.New}
+	}
+
+	return patch, changes
+}
+
+func changedFields(changes map[string]model.FieldChange) string {
+	names := make([]string, 0, len(changes))
+	for name := range changes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"effective_mobile_testing/internal/model"
+	"effective_mobile_testing/internal/service/repository"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+var ErrInvalidTeam = errors.New("invalid team")
+
+func (s *UserTaskService) CreateTeam(ctx context.Context, req model.CreateTeamRequest) (*model.Team, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidTeam)
+	}
+	if req.Kind == "" {
+		req.Kind = model.TeamKindTeam
+	}
+	if req.Kind != model.TeamKindTeam && req.Kind != model.TeamKindDepartment {
+		return nil, fmt.Errorf("%w: kind must be team or department", ErrInvalidTeam)
+	}
+
+	team, err := s.repo.CreateTeam(req.Name, req.Kind, req.ParentID)
+	if err != nil {
+		if !errors.Is(err, repository.ErrTeamExists) && !errors.Is(err, repository.ErrTeamNotFound) {
+			slog.Error("can't create team", slog.String("err", err.Error()))
+		}
+		return nil, err
+	}
+
+	return team, nil
+}
+
+func (s *UserTaskService) GetTeams(ctx context.Context) ([]model.Team, error) {
+	if err := requireManager(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListTeams()
+}
+
+// GetTeam returns the team with every membership period, to admins and the managers of the team.
+func (s *UserTaskService) GetTeam(ctx context.Context, id int64) (*model.TeamDetail, error) {
+	if err := s.requireTeam(ctx, id); err != nil {
+		return nil, err
+	}
+
+	team, err := s.repo.GetTeam(id)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.repo.GetTeamMembers(id)
+	if err != nil {
+		slog.Error("can't get team members", slog.String("err", err.Error()))
+		return nil, err
+	}
+
+	return &model.TeamDetail{Team: *team, Members: members}, nil
+}
+
+func (s *UserTaskService) DeleteTeam(ctx context.Context, id int64) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	return s.repo.DeleteTeam(id)
+}
+
+func (s *UserTaskService) AddTeamMember(ctx context.Context, teamID int64, req model.AddTeamMemberRequest) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	if req.Role == "" {
+		req.Role = model.TeamRoleMember
+	}
+	if req.Role != model.TeamRoleMember && req.Role != model.TeamRoleManager {
+		return fmt.Errorf("%w: role must be member or manager", ErrInvalidTeam)
+	}
+
+	validFrom := today()
+	if req.ValidFrom != "" {
+		d, err := time.Parse(time.DateOnly, req.ValidFrom)
+		if err != nil {
+			return fmt.Errorf("%w: valid_from must be YYYY-MM-DD", ErrInvalidTeam)
+		}
+		validFrom = d
+	}
+
+	var validTo *time.Time
+	if req.ValidTo != "" {
+		d, err := time.Parse(time.DateOnly, req.ValidTo)
+		if err != nil {
+			return fmt.Errorf("%w: valid_to must be YYYY-MM-DD", ErrInvalidTeam)
+		}
+		if !d.After(validFrom) {
+			return fmt.Errorf("%w: valid_to must be after valid_from", ErrInvalidTeam)
+		}
+		validTo = &d
+	}
+
+	if err := s.repo.AddTeamMember(teamID, req.PersonID, req.Role, validFrom, validTo); err != nil {
+		if !errors.Is(err, repository.ErrTeamNotFound) && !errors.Is(err, repository.ErrUserNotFound) &&
+			!errors.Is(err, repository.ErrMembershipOverlaps) {
+			slog.Error("can't add team member", slog.String("err", err.Error()))
+		}
+		return err
+	}
+
+	return nil
+}
+
+// EndTeamMember ends the membership at the date (today when empty), the person stays in reports before it.
+func (s *UserTaskService) EndTeamMember(ctx context.Context, teamID, personID int64, at string) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	end := today()
+	if at != "" {
+		d, err := time.Parse(time.DateOnly, at)
+		if err != nil {
+			return fmt.Errorf("%w: date must be YYYY-MM-DD", ErrInvalidTeam)
+		}
+		end = d
+	}
+
+	return s.repo.EndTeamMember(teamID, personID, end)
+}
+
+// GetTeamReport aggregates the time tracked by the team members in [from, to).
+func (s *UserTaskService) GetTeamReport(ctx context.Context, id int64, from, to time.Time) (*model.TeamReport, error) {
+	if err := s.requireTeam(ctx, id); err != nil {
+		return nil, err
+	}
+
+	if !to.After(from) {
+		return nil, fmt.Errorf("%w: to must be after from", ErrInvalidTeam)
+	}
+
+	team, err := s.repo.GetTeam(id)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.repo.GetTeamReport(id, from, to, time.Now())
+	if err != nil {
+		slog.Error("can't get team report", slog.String("err", err.Error()))
+		return nil, err
+	}
+
+	report := &model.TeamReport{Team: *team, From: from, To: to, Members: members}
+	for _, m := range members {
+		report.TotalMinutes += m.Minutes
+	}
+
+	return report, nil
+}
+
+func today() time.Time {
+	now := time.Now()
+
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -1,10 +1,15 @@
 package validators
 
 import (
+	"effective_mobile_testing/internal/model"
 	"errors"
+	"fmt"
 	"github.com/lib/pq"
+	"strings"
 )
 
+var ErrInvalidField = errors.New("invalid field")
+
 func CheckLenPassport(passport []string) bool {
 	if len(passport) != 2 {
 		return false
@@ -29,3 +34,36 @@ func IsConstrainError(err error) (error, bool) {
 
 	return err, false
 }
+
+func IsMatchMode(mode string) bool {
+	switch mode {
+	case model.MatchExact, model.MatchPrefix, model.MatchContains, model.MatchFuzzy:
+		return true
+	}
+
+	return false
+}
+
+// CheckUserPatch validates only the fields present in the patch. Patronymic is the
+// only field that may be cleared with null.
+func CheckUserPatch(patch model.UserPatch) error {
+	for _, f := range []struct {
+		name  string
+		field model.PatchField
+	}{
+		{"surname", patch.Surname},
+		{"name", patch.Name},
+		{"address", patch.Address},
+		{"passport_number", patch.PassportNumber},
+	} {
+		if f.field.Set && (f.field.Value == nil || strings.TrimSpace(*f.field.Value) == "") {
+			return fmt.Errorf("%w: %s can't be empty", ErrInvalidField, f.name)
+		}
+	}
+
+	if patch.PassportNumber.Set && !CheckLenPassport(strings.Split(*patch.PassportNumber.Value, " ")) {
+		return fmt.Errorf("%w: passport_number must look like \"1234 123456\"", ErrInvalidField)
+	}
+
+	return nil
+}
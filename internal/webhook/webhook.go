@@ -0,0 +1,366 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"effective_mobile_testing/internal/auth"
+	"effective_mobile_testing/internal/config"
+	"effective_mobile_testing/internal/model"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+
+	maxErrorLen = 512
+)
+
+var Events = []string{
+	model.EventUserCreated,
+	model.EventUserUpdated,
+	model.EventUserDeleted,
+	model.EventUserRestored,
+	model.EventUserMerged,
+	model.EventUserErased,
+	model.EventTrackingStarted,
+	model.EventTrackingStopped,
+}
+
+var (
+	ErrInvalidURL   = errors.New("webhook url must be absolute http(s) url")
+	ErrUnknownEvent = errors.New("unknown event")
+	ErrNoEvents     = errors.New("at least one event is required")
+	// ErrInternalTarget keeps webhooks from reaching services inside the network.
+	ErrInternalTarget = errors.New("webhook url must not point to a private, loopback or link-local address")
+	// ErrDeliveryExists means the outbox message was already handed to the webhook.
+	ErrDeliveryExists = errors.New("delivery of the message already exists")
+	// webhooks see every event, so only admins manage them
+	ErrForbidden = fmt.Errorf("%w: admin role required", auth.ErrForbidden)
+)
+
+type Repository interface {
+	CreateWebhook(url, secret string, events []string) (*model.Webhook, error)
+	ListWebhooks() ([]model.Webhook, error)
+	GetWebhook(id int64) (*model.Webhook, error)
+	DeleteWebhook(id int64) error
+	GetWebhooksForEvent(event string) ([]model.Webhook, error)
+	CreateDelivery(webhookID int64, outboxID *int64, event string, payload []byte) (*model.WebhookDelivery, error)
+	ClaimDeliveries(limit int, lease time.Duration) ([]Due, error)
+	UpdateDelivery(d *model.WebhookDelivery) error
+	GetDelivery(id int64) (*model.WebhookDelivery, error)
+	ListDeliveries(filter model.DeliveryFilter) (*model.DeliveryPage, error)
+}
+
+// Due is a pending delivery whose next attempt is due, with the webhook it goes to.
+type Due struct {
+	Webhook  model.Webhook
+	Delivery model.WebhookDelivery
+}
+
+// Dispatcher stores webhook subscriptions and delivers events to them in the background.
+type Dispatcher struct {
+	repo         Repository
+	client       *http.Client
+	maxAttempts  int
+	baseDelay    time.Duration
+	interval     time.Duration
+	batch        int
+	allowPrivate bool
+}
+
+func NewDispatcher(repo Repository) *Dispatcher {
+	allowPrivate := config.GetWebhookAllowPrivate()
+	dialer := &net.Dialer{Timeout: config.GetWebhookTimeout()}
+	if !allowPrivate {
+		// checked on every connection, so neither a changed DNS answer nor a redirect reaches internal addresses
+		dialer.Control = checkDial
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &Dispatcher{
+		repo:         repo,
+		client:       &http.Client{Timeout: config.GetWebhookTimeout(), Transport: transport},
+		maxAttempts:  config.GetWebhookMaxAttempts(),
+		baseDelay:    config.GetWebhookBaseDelay(),
+		interval:     config.GetWebhookPollInterval(),
+		batch:        config.GetWebhookBatchSize(),
+		allowPrivate: allowPrivate,
+	}
+}
+
+// Send creates a pending delivery for every active subscription of the message event,
+// Run sends them. It is the outbox relay sink for webhooks. A message relayed again,
+// after another sink failed, doesn't create deliveries a second time.
+func (d *Dispatcher) Send(msg model.OutboxMessage) error {
+	webhooks, err := d.repo.GetWebhooksForEvent(msg.Event)
+	if err != nil {
+		return err
+	}
+
+	for _, w := range webhooks {
+		_, err := d.repo.CreateDelivery(w.ID, &msg.ID, msg.Event, msg.Payload)
+		if err != nil && !errors.Is(err, ErrDeliveryExists) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Run polls for pending deliveries that are due until ctx is done and tries each of them once.
+// A claimed delivery isn't claimed again for twice the request timeout, so instances don't send it twice.
+// A full batch is followed immediately by the next one.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		due, err := d.repo.ClaimDeliveries(d.batch, 2*d.client.Timeout)
+		if err != nil {
+			slog.Error("can't claim webhook deliveries", slog.String("err", err.Error()))
+		}
+
+		var wg sync.WaitGroup
+		for _, dd := range due {
+			wg.Add(1)
+			go func(dd Due) {
+				defer wg.Done()
+				d.attempt(dd.Webhook, &dd.Delivery)
+			}(dd)
+		}
+		wg.Wait()
+
+		if err == nil && len(due) == d.batch {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// attempt posts the payload once. A failed attempt is scheduled again with exponential backoff until attempts run out.
+func (d *Dispatcher) attempt(w model.Webhook, delivery *model.WebhookDelivery) {
+	delivery.Attempts++
+
+	code, err := d.send(w, delivery)
+	if code != 0 {
+		delivery.ResponseCode = &code
+	}
+
+	if err == nil {
+		now := time.Now()
+		delivery.Status = model.DeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = nil
+		delivery.NextAttemptAt = nil
+		d.save(delivery)
+		return
+	}
+
+	msg := err.Error()
+	if len(msg) > maxErrorLen {
+		msg = msg[:maxErrorLen]
+	}
+	delivery.LastError = &msg
+
+	if delivery.Attempts >= d.maxAttempts {
+		delivery.Status = model.DeliveryFailed
+		delivery.NextAttemptAt = nil
+		d.save(delivery)
+		slog.Error("webhook delivery failed", slog.Int64("delivery", delivery.ID), slog.Int64("webhook", w.ID))
+		return
+	}
+
+	next := time.Now().Add(d.baseDelay << (delivery.Attempts - 1))
+	delivery.NextAttemptAt = &next
+	d.save(delivery)
+}
+
+func (d *Dispatcher) send(w model.Webhook, delivery *model.WebhookDelivery) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(w.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+func (d *Dispatcher) save(delivery *model.WebhookDelivery) {
+	if err := d.repo.UpdateDelivery(delivery); err != nil {
+		slog.Error("can't update delivery", slog.Int64("delivery", delivery.ID), slog.String("err", err.Error()))
+	}
+}
+
+// Sign returns the value of the signature header: hex HMAC-SHA256 of "<timestamp>.<body>" keyed by
+// the webhook secret. Receivers check the timestamp header is recent, so a captured delivery can't be replayed.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// internalIP tells addresses webhooks must not reach: private, loopback, link-local and unspecified.
+func internalIP(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified()
+}
+
+func checkDial(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); ip == nil || internalIP(ip) {
+		return fmt.Errorf("%w: %s", ErrInternalTarget, host)
+	}
+
+	return nil
+}
+
+// checkTarget resolves the url host and rejects it when any of its addresses is internal.
+func (d *Dispatcher) checkTarget(ctx context.Context, u *url.URL) error {
+	if d.allowPrivate {
+		return nil
+	}
+
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("%w: can't resolve %s", ErrInvalidURL, u.Hostname())
+	}
+
+	for _, ip := range ips {
+		if internalIP(ip.IP) {
+			return fmt.Errorf("%w: %s", ErrInternalTarget, ip.IP)
+		}
+	}
+
+	return nil
+}
+
+func (d *Dispatcher) Subscribe(ctx context.Context, req model.CreateWebhookRequest) (*model.Webhook, error) {
+	if !auth.HasRole(ctx, auth.RoleAdmin) {
+		return nil, ErrForbidden
+	}
+
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ErrInvalidURL
+	}
+
+	if err := d.checkTarget(ctx, u); err != nil {
+		return nil, err
+	}
+
+	if len(req.Events) == 0 {
+		return nil, ErrNoEvents
+	}
+
+	for _, e := range req.Events {
+		if !slices.Contains(Events, e) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownEvent, e)
+		}
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		secret = hex.EncodeToString(b)
+	}
+
+	return d.repo.CreateWebhook(req.URL, secret, req.Events)
+}
+
+func (d *Dispatcher) Webhooks(ctx context.Context) ([]model.Webhook, error) {
+	if !auth.HasRole(ctx, auth.RoleAdmin) {
+		return nil, ErrForbidden
+	}
+
+	return d.repo.ListWebhooks()
+}
+
+func (d *Dispatcher) Unsubscribe(ctx context.Context, id int64) error {
+	if !auth.HasRole(ctx, auth.RoleAdmin) {
+		return ErrForbidden
+	}
+
+	return d.repo.DeleteWebhook(id)
+}
+
+func (d *Dispatcher) Deliveries(ctx context.Context, filter model.DeliveryFilter) (*model.DeliveryPage, error) {
+	if !auth.HasRole(ctx, auth.RoleAdmin) {
+		return nil, ErrForbidden
+	}
+
+	filter.Limit = config.GetPageSize(filter.Limit)
+
+	return d.repo.ListDeliveries(filter)
+}
+
+// Replay stores a new pending delivery with the payload of a stored one, Run sends it.
+func (d *Dispatcher) Replay(ctx context.Context, deliveryID int64) (*model.WebhookDelivery, error) {
+	if !auth.HasRole(ctx, auth.RoleAdmin) {
+		return nil, ErrForbidden
+	}
+
+	old, err := d.repo.GetDelivery(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := d.repo.GetWebhook(old.WebhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.repo.CreateDelivery(w.ID, nil, old.Event, old.Payload)
+}
@@ -0,0 +1,145 @@
+package webhook
+
+import (
+	"context"
+	"effective_mobile_testing/internal/auth"
+	"effective_mobile_testing/internal/model"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type fakeRepo struct {
+	Repository
+	saved []model.WebhookDelivery
+}
+
+func (r *fakeRepo) UpdateDelivery(d *model.WebhookDelivery) error {
+	r.saved = append(r.saved, *d)
+	return nil
+}
+
+func (r *fakeRepo) CreateWebhook(url, secret string, events []string) (*model.Webhook, error) {
+	return &model.Webhook{URL: url, Secret: secret, Events: events}, nil
+}
+
+func newTestDispatcher(repo Repository, allowPrivate bool) *Dispatcher {
+	dialer := &net.Dialer{}
+	if !allowPrivate {
+		dialer.Control = checkDial
+	}
+
+	return &Dispatcher{
+		repo:         repo,
+		client:       &http.Client{Timeout: time.Second, Transport: &http.Transport{DialContext: dialer.DialContext}},
+		maxAttempts:  2,
+		baseDelay:    time.Minute,
+		allowPrivate: allowPrivate,
+	}
+}
+
+func TestAttemptSignsTimestampAndBody(t *testing.T) {
+	var got *http.Request
+	var body []byte
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+	}))
+	t.Cleanup(srv.Close)
+
+	repo := &fakeRepo{}
+	d := newTestDispatcher(repo, true)
+
+	d.attempt(model.Webhook{URL: srv.URL, Secret: "s"}, &model.WebhookDelivery{ID: 1, Payload: []byte(`{"id":1}`)})
+
+	ts, err := strconv.ParseInt(got.Header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		t.Fatalf("timestamp header: %v", err)
+	}
+	if want := Sign("s", ts, body); got.Header.Get(SignatureHeader) != want {
+		t.Fatalf("signature %q, want %q", got.Header.Get(SignatureHeader), want)
+	}
+	if Sign("s", ts+1, body) == Sign("s", ts, body) {
+		t.Fatal("signature doesn't depend on the timestamp")
+	}
+
+	if len(repo.saved) != 1 || repo.saved[0].Status != model.DeliveryDelivered || repo.saved[0].NextAttemptAt != nil {
+		t.Fatalf("saved %+v, want one delivered", repo.saved)
+	}
+}
+
+func TestAttemptSchedulesRetryThenFails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(srv.Close)
+
+	repo := &fakeRepo{}
+	d := newTestDispatcher(repo, true)
+	delivery := &model.WebhookDelivery{ID: 1, Status: model.DeliveryPending}
+
+	d.attempt(model.Webhook{URL: srv.URL}, delivery)
+
+	first := repo.saved[0]
+	if first.Status != model.DeliveryPending || first.NextAttemptAt == nil || time.Until(*first.NextAttemptAt) < 50*time.Second {
+		t.Fatalf("first attempt saved %+v, want pending retry in a minute", first)
+	}
+
+	d.attempt(model.Webhook{URL: srv.URL}, delivery)
+
+	second := repo.saved[1]
+	if second.Status != model.DeliveryFailed || second.NextAttemptAt != nil || second.Attempts != 2 {
+		t.Fatalf("second attempt saved %+v, want failed", second)
+	}
+}
+
+func TestAttemptRefusesInternalAddress(t *testing.T) {
+	var called bool
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	t.Cleanup(srv.Close)
+
+	repo := &fakeRepo{}
+	d := newTestDispatcher(repo, false)
+
+	d.attempt(model.Webhook{URL: srv.URL}, &model.WebhookDelivery{ID: 1})
+
+	if called {
+		t.Fatal("loopback webhook was called")
+	}
+	if repo.saved[0].LastError == nil {
+		t.Fatal("no error saved")
+	}
+}
+
+func TestSubscribeRejectsInternalTargets(t *testing.T) {
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{Name: "admin", Roles: []string{auth.RoleAdmin}})
+	d := newTestDispatcher(&fakeRepo{}, false)
+
+	for _, u := range []string{
+		"http://127.0.0.1/hook",
+		"http://localhost:8080/hook",
+		"http://10.0.0.5/hook",
+		"http://192.168.1.1/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]/hook",
+		"http://0.0.0.0/hook",
+	} {
+		_, err := d.Subscribe(ctx, model.CreateWebhookRequest{URL: u, Events: []string{model.EventUserCreated}})
+		if !errors.Is(err, ErrInternalTarget) {
+			t.Errorf("%s: got %v, want ErrInternalTarget", u, err)
+		}
+	}
+
+	if _, err := d.Subscribe(ctx, model.CreateWebhookRequest{URL: "http://8.8.8.8/hook", Events: []string{model.EventUserCreated}}); err != nil {
+		t.Errorf("public address: %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+create table if not exists webhook
+(
+    id serial primary key,
+    url text not null,
+    secret text not null,
+    events text[] not null,
+    active boolean not null default true,
+    created_at timestamp not null default now()
+);
+
+create table if not exists webhook_delivery
+(
+    id bigserial primary key,
+    webhook_id bigint not null references webhook(id) on delete cascade,
+    event text not null,
+    payload jsonb not null,
+    status varchar(16) not null,
+    attempts int not null default 0,
+    response_code int,
+    last_error text,
+    created_at timestamp not null default now(),
+    delivered_at timestamp
+);
+
+create index if not exists webhook_delivery_webhook_id_idx on webhook_delivery (webhook_id, id desc);
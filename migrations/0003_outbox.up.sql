@@ -0,0 +1,10 @@
+create table if not exists outbox
+(
+    id bigserial primary key,
+    event text not null,
+    payload jsonb not null,
+    created_at timestamp not null default now(),
+    published_at timestamp
+);
+
+create index if not exists outbox_unpublished_idx on outbox (id) where published_at is null;
@@ -0,0 +1,12 @@
+drop index person_address_trgm_idx;
+
+drop index person_patronymic_trgm_idx;
+
+drop index person_name_trgm_idx;
+
+drop index person_surname_trgm_idx;
+
+drop function normalize_text(text);
+
+
+drop extension if exists pg_trgm;
\ No newline at end of file
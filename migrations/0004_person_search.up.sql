@@ -0,0 +1,12 @@
+create extension if not exists pg_trgm;
+
+-- lower case and ё -> е, so "Ёлкин", "ёлкин" and "елкин" are the same for search
+create or replace function normalize_text(s text) returns text as
+$$
+select replace(lower(s), 'ё', 'е')
+$$ language sql immutable strict parallel safe;
+
+create index if not exists person_surname_trgm_idx on person using gin (normalize_text(surname) gin_trgm_ops);
+create index if not exists person_name_trgm_idx on person using gin (normalize_text(name) gin_trgm_ops);
+create index if not exists person_patronymic_trgm_idx on person using gin (normalize_text(patronymic) gin_trgm_ops);
+create index if not exists person_address_trgm_idx on person using gin (normalize_text(address) gin_trgm_ops);
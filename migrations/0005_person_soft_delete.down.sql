@@ -0,0 +1,9 @@
+drop index person_deleted_at_idx;
+
+drop index person_passport_number_active_idx;
+
+alter table person add constraint person_passport_number_key unique (passport_number);
+
+alter table person drop column anonymized_at;
+
+alter table person drop column deleted_at;
@@ -0,0 +1,8 @@
+alter table person add column if not exists deleted_at timestamp;
+alter table person add column if not exists anonymized_at timestamp;
+
+-- a deleted person must not block creating the same passport again
+alter table person drop constraint if exists person_passport_number_key;
+create unique index if not exists person_passport_number_active_idx on person (passport_number) where deleted_at is null;
+
+create index if not exists person_deleted_at_idx on person (deleted_at) where deleted_at is not null;
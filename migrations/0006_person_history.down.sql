@@ -0,0 +1 @@
+drop table person_history;
\ No newline at end of file
@@ -0,0 +1,11 @@
+create table if not exists person_history
+(
+    id bigserial primary key,
+    person_id bigint not null references person(id) on delete cascade,
+    action varchar(16) not null,
+    actor text not null,
+    changes jsonb not null,
+    changed_at timestamp not null default now()
+);
+
+create index if not exists person_history_person_id_idx on person_history (person_id, id desc);
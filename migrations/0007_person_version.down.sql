@@ -0,0 +1 @@
+alter table person drop column if exists version;
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- bumped on every write, exposed as the ETag for optimistic concurrency
+alter table person add column if not exists version bigint not null default 1;
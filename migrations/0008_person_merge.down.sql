@@ -0,0 +1 @@
+alter table person drop column if exists merged_into;
\ No newline at end of file
@@ -0,0 +1,6 @@
+-- a merged person is soft deleted and points to the survivor, so its id still resolves.
+-- deferred so a backup can be restored in id order
+alter table person add column if not exists merged_into bigint
+    references person(id) on delete set null deferrable initially deferred;
+
+create index if not exists person_merged_into_idx on person (merged_into) where merged_into is not null;
@@ -0,0 +1,16 @@
+-- once the service has started passports exist only encrypted, passport_number is emptied.
+-- Dropping passport_enc would lose them, so the downgrade stops until they are decrypted back
+-- into passport_number. The masking of passports in person_history can't be undone.
+do $$
+begin
+    if exists (select 1 from person where passport_enc is not null) then
+        raise exception 'person.passport_enc holds encrypted passports, decrypt them into passport_number before downgrading';
+    end if;
+end
+$$;
+
+drop index if exists person_passport_number_hash_idx;
+drop index if exists person_passport_hash_active_idx;
+alter table person drop column if exists passport_number_hash;
+alter table person drop column if exists passport_hash;
+alter table person drop column if exists passport_enc;
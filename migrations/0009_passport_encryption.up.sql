@@ -0,0 +1,17 @@
+-- passport numbers are encrypted by the application (passport_enc) and found by keyed blind indexes.
+-- the service moves existing plain text numbers out of passport_number at startup
+alter table person add column if not exists passport_enc text;
+alter table person add column if not exists passport_hash char(64);
+alter table person add column if not exists passport_number_hash char(64);
+
+create unique index if not exists person_passport_hash_active_idx on person (passport_hash) where deleted_at is null;
+create index if not exists person_passport_number_hash_idx on person (passport_number_hash);
+
+-- history keeps passports masked from now on, mask what was recorded before.
+-- This is one-way: the down migration doesn't bring the recorded passports back
+update person_history set changes = jsonb_set(changes, '{passport_number}', jsonb_build_object(
+        'old', case when changes -> 'passport_number' ->> 'old' is null then null
+                    else '**** ****' || right(changes -> 'passport_number' ->> 'old', 2) end,
+        'new', case when changes -> 'passport_number' ->> 'new' is null then null
+                    else '**** ****' || right(changes -> 'passport_number' ->> 'new', 2) end))
+where changes ? 'passport_number';
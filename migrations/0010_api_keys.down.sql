@@ -0,0 +1 @@
+drop table api_key;
\ No newline at end of file
@@ -0,0 +1,11 @@
+create table if not exists api_key
+(
+    id bigserial primary key,
+    name text not null,
+    prefix varchar(16) not null,
+    key_hash char(64) not null unique,
+    roles text[] not null default '{}',
+    created_at timestamp not null default now(),
+    last_used_at timestamp,
+    revoked_at timestamp
+);
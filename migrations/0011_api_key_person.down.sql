@@ -0,0 +1 @@
+alter table api_key drop column if exists person_id;
@@ -0,0 +1 @@
+alter table api_key add column if not exists person_id bigint references person(id) on delete set null;
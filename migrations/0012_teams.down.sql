@@ -0,0 +1,2 @@
+drop table if exists team_member;
+drop table if exists team;
@@ -0,0 +1,23 @@
+create table if not exists team
+(
+    id bigserial primary key,
+    name text not null unique,
+    kind text not null default 'team' check (kind in ('team', 'department')),
+    parent_id bigint references team(id) on delete set null,
+    created_at timestamp not null default now()
+);
+
+-- valid_from is inclusive, valid_to exclusive, null means the membership is open ended
+create table if not exists team_member
+(
+    id bigserial primary key,
+    team_id bigint not null references team(id) on delete cascade,
+    person_id bigint not null references person(id) on delete cascade,
+    role text not null default 'member' check (role in ('member', 'manager')),
+    valid_from date not null default current_date,
+    valid_to date check (valid_to > valid_from)
+);
+
+create index if not exists team_member_person_idx on team_member (person_id);
+create index if not exists team_member_team_idx on team_member (team_id);
+create index if not exists team_parent_idx on team (parent_id);
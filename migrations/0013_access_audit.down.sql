@@ -0,0 +1,2 @@
+drop table if exists access_audit;
+drop function if exists access_audit_append_only();
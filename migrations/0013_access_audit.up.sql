@@ -0,0 +1,24 @@
+create table if not exists access_audit
+(
+    id bigserial primary key,
+    actor text not null,
+    action text not null,
+    filter jsonb not null default '{}',
+    person_ids bigint[] not null default '{}',
+    created_at timestamptz not null default now()
+);
+
+create index if not exists access_audit_person_ids_idx on access_audit using gin (person_ids);
+create index if not exists access_audit_actor_idx on access_audit (actor, id);
+
+-- the audit is append-only, rows can't be changed or removed
+create or replace function access_audit_append_only() returns trigger as $$
+begin
+    raise exception 'access_audit is append-only';
+end
+$$ language plpgsql;
+
+create trigger access_audit_no_change before update or delete on access_audit
+    for each row execute function access_audit_append_only();
+create trigger access_audit_no_truncate before truncate on access_audit
+    for each statement execute function access_audit_append_only();
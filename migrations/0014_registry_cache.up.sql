@@ -0,0 +1,10 @@
+-- registry answers shared by all instances, keyed by the passport blind index like person.passport_hash
+create table if not exists registry_cache
+(
+    passport_hash char(64) primary key,
+    -- null when the registry doesn't know the passport
+    data jsonb,
+    expires_at timestamptz not null
+);
+
+create index if not exists registry_cache_expires_at_idx on registry_cache (expires_at);
@@ -0,0 +1,2 @@
+drop index if exists webhook_delivery_outbox_idx;
+alter table webhook_delivery drop column if exists outbox_id;
@@ -0,0 +1,5 @@
+-- the outbox message a delivery was created for, so a message relayed again doesn't create it twice.
+-- Replayed deliveries have none, nulls never conflict.
+alter table webhook_delivery add column if not exists outbox_id bigint;
+
+create unique index if not exists webhook_delivery_outbox_idx on webhook_delivery (webhook_id, outbox_id);
@@ -0,0 +1,2 @@
+truncate registry_cache;
+alter table registry_cache alter column data type jsonb using null;
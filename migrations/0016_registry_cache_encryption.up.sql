@@ -0,0 +1,3 @@
+-- cached persons are encrypted with the passport keys like passports, entries stored in plain text are dropped
+truncate registry_cache;
+alter table registry_cache alter column data type text using null;
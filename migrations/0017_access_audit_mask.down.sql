@@ -0,0 +1 @@
+-- the masked filters can't be brought back
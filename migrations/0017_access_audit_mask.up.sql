@@ -0,0 +1,16 @@
+-- filters are recorded masked from now on, mask the ones recorded before.
+-- The table is append-only, this is the one change allowed to its rows
+alter table access_audit disable trigger access_audit_no_change;
+
+update access_audit set filter = (
+    select jsonb_object_agg(key, case
+        when key in ('surname', 'name', 'patronymic', 'q') then to_jsonb(coalesce(
+            (select string_agg(left(w, 1) || '***', ' ') from regexp_split_to_table(btrim(value #>> '{}'), '\s+') w where w <> ''), ''))
+        when key = 'address' then to_jsonb(case when strpos(value #>> '{}', ',') > 0
+            then split_part(value #>> '{}', ',', 1) || ', ***' else '***' end)
+        when key = 'cursor' then '"***"'::jsonb
+        else value end)
+    from jsonb_each(filter))
+where filter ?| array ['surname', 'name', 'patronymic', 'q', 'address', 'cursor'];
+
+alter table access_audit enable trigger access_audit_no_change;
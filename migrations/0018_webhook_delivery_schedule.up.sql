@@ -0,0 +1,6 @@
+-- when a pending delivery is tried next. Deliveries are polled from the table, so retries survive restarts.
+alter table webhook_delivery add column if not exists next_attempt_at timestamptz;
+
+update webhook_delivery set next_attempt_at = now() where status = 'pending';
+
+create index if not exists webhook_delivery_due_idx on webhook_delivery (next_attempt_at) where status = 'pending';
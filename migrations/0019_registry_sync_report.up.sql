@@ -0,0 +1,9 @@
+-- the report of the last scheduled registry sync, so it survives restarts. It holds personal data,
+-- so it is stored as JSON encrypted with the passport keys; person_ids lets erasure find it.
+create table if not exists registry_sync_report
+(
+    id bigserial primary key,
+    report text not null,
+    person_ids bigint[] not null,
+    finished_at timestamptz not null
+);
@@ -0,0 +1,2 @@
+-- stripped payloads can't be restored
+drop index if exists outbox_published_at_idx;
@@ -0,0 +1,13 @@
+-- user events carried the whole person with the decrypted passport. Keep only the id and version
+-- in stored payloads, the same as new events.
+update outbox set payload = jsonb_set(payload, '{data}', jsonb_build_object(
+        'id', (payload -> 'data' ->> 'id')::bigint,
+        'version', coalesce((payload -> 'data' ->> 'version')::bigint, 0)))
+    where event in ('user.created', 'user.updated', 'user.restored');
+
+update webhook_delivery set payload = jsonb_set(payload, '{data}', jsonb_build_object(
+        'id', (payload -> 'data' ->> 'id')::bigint,
+        'version', coalesce((payload -> 'data' ->> 'version')::bigint, 0)))
+    where event in ('user.created', 'user.updated', 'user.restored');
+
+create index if not exists outbox_published_at_idx on outbox (published_at) where published_at is not null;